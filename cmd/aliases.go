@@ -0,0 +1,245 @@
+/*
+Copyright © 2023 shalomb <s.bhooshi@gmail.com>
+*/
+package cmd
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// Aliases are user-defined commands made of a sequence of gum commands,
+// declared in the config file, e.g.
+//
+//	commands:
+//	  morning: ["update", "dirs --width 80"]
+//	  open: ["edit --print $1"]
+//
+// Steps run in order in the same process and the alias stops at the first
+// step that fails. $1, $2, ... in a step are replaced by the arguments given
+// to the alias. A step may run another alias, as long as no alias ends up
+// running itself.
+
+const aliasGroup = "aliases"
+
+// aliasParam matches the positional parameters in alias steps.
+var aliasParam = regexp.MustCompile(`\$(\d+)`)
+
+// registerAliases validates the aliases in the config and adds a command
+// for each of them.
+func registerAliases() error {
+	aliases := viper.GetStringMapStringSlice("commands")
+	if len(aliases) == 0 {
+		return nil
+	}
+
+	builtin := map[string]bool{"help": true}
+	for _, c := range rootCmd.Commands() {
+		builtin[c.Name()] = true
+		for _, a := range c.Aliases {
+			builtin[a] = true
+		}
+	}
+
+	names := make([]string, 0, len(aliases))
+	for name := range aliases {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if builtin[name] {
+			return fmt.Errorf("alias %q: a gum command of that name already exists", name)
+		}
+		if len(aliases[name]) == 0 {
+			return fmt.Errorf("alias %q: no commands to run", name)
+		}
+		for _, step := range aliases[name] {
+			words := strings.Fields(step)
+			if len(words) == 0 {
+				return fmt.Errorf("alias %q: empty command", name)
+			}
+			if _, ok := aliases[words[0]]; !ok && !builtin[words[0]] {
+				return fmt.Errorf("alias %q: unknown command %q in %q", name, words[0], step)
+			}
+		}
+	}
+
+	if cycle := findAliasCycle(aliases, names); cycle != nil {
+		return fmt.Errorf("aliases run each other in a loop: %v", strings.Join(cycle, " -> "))
+	}
+
+	rootCmd.AddGroup(&cobra.Group{ID: aliasGroup, Title: "Aliases:"})
+	for _, name := range names {
+		rootCmd.AddCommand(newAliasCmd(name, aliases[name]))
+	}
+	return nil
+}
+
+// findAliasCycle returns the aliases forming a loop, if any.
+func findAliasCycle(aliases map[string][]string, names []string) []string {
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := map[string]int{}
+
+	var visit func(name string, path []string) []string
+	visit = func(name string, path []string) []string {
+		path = append(path, name)
+		switch state[name] {
+		case visiting:
+			return path
+		case done:
+			return nil
+		}
+
+		state[name] = visiting
+		for _, step := range aliases[name] {
+			next := strings.Fields(step)[0]
+			if _, ok := aliases[next]; !ok {
+				continue
+			}
+			if cycle := visit(next, path); cycle != nil {
+				return cycle
+			}
+		}
+		state[name] = done
+		return nil
+	}
+
+	for _, name := range names {
+		if cycle := visit(name, nil); cycle != nil {
+			return cycle
+		}
+	}
+	return nil
+}
+
+// newAliasCmd returns the command running the steps of an alias.
+func newAliasCmd(name string, steps []string) *cobra.Command {
+	nargs := 0
+	for _, step := range steps {
+		for _, m := range aliasParam.FindAllStringSubmatch(step, -1) {
+			if n, _ := strconv.Atoi(m[1]); n > nargs {
+				nargs = n
+			}
+		}
+	}
+
+	use := name
+	for i := 1; i <= nargs; i++ {
+		use += fmt.Sprintf(" <arg%d>", i)
+	}
+
+	return &cobra.Command{
+		Use:          use,
+		Short:        "Alias for: " + strings.Join(steps, "; "),
+		GroupID:      aliasGroup,
+		SilenceUsage: true,
+		Args:         cobra.ExactArgs(nargs),
+
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// The global flags given before the alias apply to every step.
+			restoreGlobal := saveFlags(rootCmd.PersistentFlags())
+			defer restoreGlobal()
+
+			for i, step := range steps {
+				if err := runAliasStep(expandAliasStep(step, args), restoreGlobal); err != nil {
+					return fmt.Errorf("%v: step %d (%v) failed: %w", name, i+1, step, err)
+				}
+			}
+			return nil
+		},
+	}
+}
+
+// expandAliasStep splits step into words and substitutes the positional
+// parameters. A word that is just a parameter stays one word even if the
+// argument contains spaces.
+func expandAliasStep(step string, args []string) []string {
+	words := strings.Fields(step)
+	for i, word := range words {
+		words[i] = aliasParam.ReplaceAllStringFunc(word, func(param string) string {
+			n, _ := strconv.Atoi(param[1:])
+			if n < 1 || n > len(args) {
+				return ""
+			}
+			return args[n-1]
+		})
+	}
+	return words
+}
+
+// runAliasStep runs gum with argv in this process, with the global flags
+// as restoreGlobal sets them.
+func runAliasStep(argv []string, restoreGlobal func()) error {
+	// Flag values stick to the commands between executions, so put them
+	// back to their defaults for each step, but for the global flags.
+	resetFlags(rootCmd)
+	restoreGlobal()
+
+	silenceErrors, silenceUsage := rootCmd.SilenceErrors, rootCmd.SilenceUsage
+	rootCmd.SilenceErrors, rootCmd.SilenceUsage = true, true
+	defer func() {
+		rootCmd.SilenceErrors, rootCmd.SilenceUsage = silenceErrors, silenceUsage
+	}()
+
+	rootCmd.SetArgs(argv)
+	_, err := rootCmd.ExecuteC()
+	return err
+}
+
+// saveFlags returns a function that sets the flags of fs back to their
+// current values.
+func saveFlags(fs *pflag.FlagSet) func() {
+	type state struct {
+		values  []string
+		changed bool
+	}
+	saved := map[*pflag.Flag]state{}
+	fs.VisitAll(func(f *pflag.Flag) {
+		if s, ok := f.Value.(pflag.SliceValue); ok {
+			saved[f] = state{s.GetSlice(), f.Changed}
+		} else {
+			saved[f] = state{[]string{f.Value.String()}, f.Changed}
+		}
+	})
+
+	return func() {
+		for f, st := range saved {
+			if s, ok := f.Value.(pflag.SliceValue); ok {
+				_ = s.Replace(st.values)
+			} else {
+				_ = f.Value.Set(st.values[0])
+			}
+			f.Changed = st.changed
+		}
+	}
+}
+
+// resetFlags sets every flag of c and its subcommands back to its default.
+func resetFlags(c *cobra.Command) {
+	reset := func(f *pflag.Flag) {
+		if s, ok := f.Value.(pflag.SliceValue); ok {
+			_ = s.Replace(nil)
+		} else {
+			_ = f.Value.Set(f.DefValue)
+		}
+		f.Changed = false
+	}
+	c.Flags().VisitAll(reset)
+	c.PersistentFlags().VisitAll(reset)
+
+	for _, sub := range c.Commands() {
+		resetFlags(sub)
+	}
+}
@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+// runTestAlias runs gum with argv, where the alias test-alias runs steps
+// of test-step, which records its arguments, the value of its --upper flag
+// and the global --width, and fails if its first argument is "fail".
+func runTestAlias(t *testing.T, steps []string, argv ...string) ([]string, error) {
+	t.Helper()
+
+	var calls []string
+	step := &cobra.Command{
+		Use: "test-step",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			upper, _ := cmd.Flags().GetBool("upper")
+			calls = append(calls, fmt.Sprintf("%q upper=%v width=%v", args, upper, Width))
+			if len(args) > 0 && args[0] == "fail" {
+				return errors.New("failed")
+			}
+			return nil
+		},
+	}
+	step.Flags().Bool("upper", false, "")
+	alias := newAliasCmd("test-alias", steps)
+	alias.GroupID = ""
+
+	rootCmd.AddCommand(step, alias)
+	t.Cleanup(func() {
+		rootCmd.RemoveCommand(step, alias)
+		resetFlags(rootCmd)
+		rootCmd.SetArgs(nil)
+	})
+
+	rootCmd.SetArgs(argv)
+	rootCmd.SilenceErrors, rootCmd.SilenceUsage = true, true
+	defer func() { rootCmd.SilenceErrors, rootCmd.SilenceUsage = false, false }()
+	_, err := rootCmd.ExecuteC()
+	return calls, err
+}
+
+func TestAliasRunsStepsInOrder(t *testing.T) {
+	calls, err := runTestAlias(t, []string{"test-step a $1", "test-step --upper b", "test-step c $2"},
+		"--width", "20", "test-alias", "one two", "three")
+	if err != nil {
+		t.Fatalf("alias failed: %v", err)
+	}
+	want := []string{
+		`["a" "one two"] upper=false width=20`,
+		`["b"] upper=true width=20`,
+		`["c" "three"] upper=false width=20`,
+	}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("alias ran\n%v\nwant\n%v", strings.Join(calls, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestAliasStepFlagsDoNotLeak(t *testing.T) {
+	calls, err := runTestAlias(t, []string{"test-step --width 5 a", "test-step b"}, "--width", "20", "test-alias")
+	if err != nil {
+		t.Fatalf("alias failed: %v", err)
+	}
+	want := []string{`["a"] upper=false width=5`, `["b"] upper=false width=20`}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("alias ran %q, want %q", calls, want)
+	}
+}
+
+func TestAliasStopsAtFailure(t *testing.T) {
+	calls, err := runTestAlias(t, []string{"test-step a", "test-step fail", "test-step never"}, "test-alias")
+	if err == nil || !strings.Contains(err.Error(), "step 2 (test-step fail) failed") {
+		t.Errorf("alias returned %v; want step 2 to fail", err)
+	}
+	if len(calls) != 2 {
+		t.Errorf("alias ran %q; want it to stop after the failing step", calls)
+	}
+}
+
+func TestExpandAliasStep(t *testing.T) {
+	tests := []struct {
+		step string
+		args []string
+		want []string
+	}{
+		{"projects", nil, []string{"projects"}},
+		{"edit --print $1", []string{"my project"}, []string{"edit", "--print", "my project"}},
+		{"similar $2-$1", []string{"a", "b"}, []string{"similar", "b-a"}},
+		{"similar $3", []string{"a"}, []string{"similar", ""}},
+	}
+	for _, tt := range tests {
+		if got := expandAliasStep(tt.step, tt.args); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("expandAliasStep(%q, %q) = %q, want %q", tt.step, tt.args, got, tt.want)
+		}
+	}
+}
+
+func TestFindAliasCycle(t *testing.T) {
+	aliases := map[string][]string{
+		"a": {"b"},
+		"b": {"projects", "c x"},
+		"c": {"a"},
+		"d": {"projects"},
+	}
+	if cycle := findAliasCycle(aliases, []string{"a", "b", "c", "d"}); !reflect.DeepEqual(cycle, []string{"a", "b", "c", "a"}) {
+		t.Errorf("findAliasCycle = %v, want [a b c a]", cycle)
+	}
+	delete(aliases, "c")
+	if cycle := findAliasCycle(aliases, []string{"a", "b", "d"}); cycle != nil {
+		t.Errorf("findAliasCycle = %v, want none", cycle)
+	}
+}
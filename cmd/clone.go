@@ -0,0 +1,200 @@
+/*
+Copyright © 2023 shalomb <s.bhooshi@gmail.com>
+*/
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/shalomb/gum/internal/execx"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+// cloneCmd represents the clone command
+var cloneCmd = &cobra.Command{
+	Use:   "clone <url>",
+	Short: "Clone a repository into the project directories",
+	Long: `Clone a repository with git and print where it went, so that
+
+  cd "$(gum clone https://github.com/shalomb/gum)"
+
+lands in the new clone. It goes into the first directory of the projects
+setting, named after the repository, unless --target says otherwise.
+gum projects lists it straight away if it is next to other projects.
+Elsewhere under the project directories, it may only be listed once
+updatedb has indexed it, or with --no-locate.
+
+git's progress is shown on stderr. Without a terminal, as under cron or
+--non-interactive, git is not allowed to prompt for credentials or host
+keys and fails instead. If git fails, gum exits with git's
+status. A clone that fails or is interrupted is removed rather than left
+half done. An existing target is an error, unless --force is given and
+it is already a clone of the same repository, in which case it is used
+as it is.`,
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+
+	RunE: func(cmd *cobra.Command, args []string) error {
+		target, _ := cmd.Flags().GetString("target")
+		ssh, _ := cmd.Flags().GetBool("ssh")
+		force, _ := cmd.Flags().GetBool("force")
+		return doClone(cmd.Context(), args[0], target, ssh, force)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(cloneCmd)
+
+	cloneCmd.Flags().String("target", "", "Clone into this directory")
+	cloneCmd.Flags().Bool("ssh", false, "Clone https URLs over ssh instead, as git@host:owner/repo")
+	cloneCmd.Flags().Bool("force", false, "Use an existing target if it is a clone of the same repository")
+}
+
+func doClone(ctx context.Context, remote, target string, ssh, force bool) error {
+	if ssh {
+		remote = sshURL(remote)
+	}
+
+	target, err := cloneTarget(remote, target)
+	if err != nil {
+		return err
+	}
+
+	if _, err := os.Lstat(target); err == nil {
+		if !force {
+			return fmt.Errorf("%v already exists; use --force to reuse it if it is a clone of %v", tildePath(target), remote)
+		}
+		existing, err := gitRemoteURL(target)
+		if err != nil || repoIdentity(existing) != repoIdentity(remote) {
+			return fmt.Errorf("%v already exists and is not a clone of %v", tildePath(target), remote)
+		}
+		log.Debugf("using existing clone %v", target)
+		fmt.Println(target)
+		return nil
+	}
+
+	removeParents, err := mkdirParents(target)
+	if err != nil {
+		return err
+	}
+
+	// git writes its progress to stderr; stdout is kept for the path.
+	streams := execx.Streams{Stdout: os.Stderr, Stderr: os.Stderr}
+	if isInteractive() {
+		streams.Stdin = os.Stdin
+	} else {
+		streams.Env = gitBatchEnv(os.Environ())
+	}
+	if err := runner.Stream(ctx, streams, "", "git", "clone", "--", remote, target); err != nil {
+		if rmErr := os.RemoveAll(target); rmErr != nil {
+			log.Warnf("error removing partial clone %v: %v", target, rmErr)
+		}
+		removeParents()
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return fmt.Errorf("error cloning %v: %w", remote, err)
+	}
+
+	if !withinProjectDirs(target) {
+		log.Warnf("%v is outside the project directories, so gum projects will not list it", tildePath(target))
+	}
+	fmt.Println(target)
+	return nil
+}
+
+// mkdirParents creates the missing parent directories of path and returns
+// a function that removes those it created again, if they are empty.
+func mkdirParents(path string) (func(), error) {
+	// Deepest first, the order to remove them in.
+	var created []string
+	for dir := filepath.Dir(path); dir != filepath.Dir(dir); dir = filepath.Dir(dir) {
+		if _, err := os.Lstat(dir); err == nil {
+			break
+		}
+		created = append(created, dir)
+	}
+	remove := func() {
+		for _, dir := range created {
+			os.Remove(dir)
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		remove()
+		return nil, err
+	}
+	return remove, nil
+}
+
+// gitBatchEnv returns env with what keeps git from prompting: no terminal
+// prompts for credentials and ssh in batch mode, which fails rather than
+// asking for passphrases or to trust host keys.
+func gitBatchEnv(env []string) []string {
+	ssh := "ssh"
+	for _, e := range env {
+		if v, ok := strings.CutPrefix(e, "GIT_SSH_COMMAND="); ok && v != "" {
+			ssh = v
+		}
+	}
+	return append(env, "GIT_TERMINAL_PROMPT=0", "GIT_SSH_COMMAND="+ssh+" -o BatchMode=yes")
+}
+
+// cloneTarget returns the absolute path to clone remote into: target if
+// given, else a directory named after the repository in the first project
+// directory.
+func cloneTarget(remote, target string) (string, error) {
+	if target != "" {
+		return filepath.Abs(expandPath(target))
+	}
+
+	name := strings.TrimSuffix(filepath.Base(repoIdentity(remote)), ".git")
+	if name == "" || name == "." || name == string(filepath.Separator) {
+		return "", fmt.Errorf("cannot tell a directory name from %q; use --target", remote)
+	}
+
+	dirs := projectDirs()
+	if len(dirs) == 0 || dirs[0].Path == "" {
+		return "", errors.New("no project directories configured; use --target")
+	}
+	return filepath.Join(expandPath(dirs[0].Path), name), nil
+}
+
+// withinProjectDirs reports whether path is under one of the project
+// directories that discovery searches.
+func withinProjectDirs(path string) bool {
+	for _, dir := range projectDirs() {
+		if strings.HasPrefix(dir.Path, "~/") && pathWithin(path, expandPath(dir.Path)) {
+			return true
+		}
+	}
+	return false
+}
+
+// sshURL rewrites an https or http URL such as https://github.com/o/r to
+// the scp-like ssh form git@github.com:o/r.git, or to
+// ssh://git@host:port/o/r.git if it has a port other than the default,
+// which the scp-like form cannot carry. Other URLs are returned unchanged.
+func sshURL(remote string) string {
+	u, err := url.Parse(remote)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return remote
+	}
+	path := strings.Trim(u.Path, "/")
+	if path == "" {
+		return remote
+	}
+	if !strings.HasSuffix(path, ".git") {
+		path += ".git"
+	}
+	if port := u.Port(); port != "" && !(u.Scheme == "https" && port == "443" || u.Scheme == "http" && port == "80") {
+		return "ssh://git@" + u.Host + "/" + path
+	}
+	return "git@" + u.Hostname() + ":" + path
+}
@@ -0,0 +1,183 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/shalomb/gum/internal/execx"
+)
+
+// bareRepo creates an empty bare repository to clone from.
+func bareRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	repo := filepath.Join(t.TempDir(), "source.git")
+	if out, err := exec.Command("git", "init", "--quiet", "--bare", repo).CombinedOutput(); err != nil {
+		t.Fatalf("git init failed: %v: %s", err, out)
+	}
+	return repo
+}
+
+func TestDoClone(t *testing.T) {
+	NonInteractive = true
+	t.Cleanup(func() { NonInteractive = false })
+
+	source := "file://" + bareRepo(t)
+	target := filepath.Join(t.TempDir(), "parent", "clone")
+
+	if err := doClone(context.Background(), source, target, false, false); err != nil {
+		t.Fatalf("doClone failed: %v", err)
+	}
+	if remote, err := gitRemoteURL(target); err != nil || remote != source {
+		t.Fatalf("clone in %v has remote %q, %v; want %q", target, remote, err, source)
+	}
+
+	if err := doClone(context.Background(), source, target, false, false); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("cloning onto an existing clone returned %v; want it to refuse", err)
+	}
+	if err := doClone(context.Background(), source, target, false, true); err != nil {
+		t.Errorf("cloning onto an existing clone with --force returned %v; want it reused", err)
+	}
+	if err := doClone(context.Background(), "file:///elsewhere.git", target, false, true); err == nil {
+		t.Errorf("cloning onto a clone of another repository with --force succeeded")
+	}
+}
+
+func TestDoCloneCleansUp(t *testing.T) {
+	NonInteractive = true
+	t.Cleanup(func() { NonInteractive = false })
+	bareRepo(t)
+
+	target := filepath.Join(t.TempDir(), "clone")
+	err := doClone(context.Background(), "file://"+filepath.Join(t.TempDir(), "missing.git"), target, false, false)
+	// gum exits with git's status.
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) {
+		t.Errorf("cloning a missing repository returned %v; want git's exit status", err)
+	}
+	if _, err := os.Lstat(target); !os.IsNotExist(err) {
+		t.Errorf("failed clone left %v behind", target)
+	}
+}
+
+func TestDoCloneRemovesWhatItCreated(t *testing.T) {
+	NonInteractive = true
+	saved := runner
+	t.Cleanup(func() {
+		NonInteractive = false
+		runner = saved
+	})
+	root := t.TempDir()
+	remote := "https://example.com/o/r.git"
+
+	tests := []struct {
+		name     string
+		target   string
+		existing string
+		err      error
+		kept     []string
+		removed  []string
+	}{
+		{
+			name:    "new parents",
+			target:  filepath.Join(root, "a", "b", "clone"),
+			err:     exitError(t, 128),
+			kept:    []string{root},
+			removed: []string{filepath.Join(root, "a")},
+		},
+		{
+			name:     "existing parent",
+			target:   filepath.Join(root, "existing", "new", "clone"),
+			existing: filepath.Join(root, "existing", "other"),
+			err:      exitError(t, 128),
+			kept:     []string{filepath.Join(root, "existing", "other")},
+			removed:  []string{filepath.Join(root, "existing", "new")},
+		},
+		{
+			name:   "success",
+			target: filepath.Join(root, "c", "d", "clone"),
+			kept:   []string{filepath.Join(root, "c", "d", "clone", ".git")},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.existing != "" {
+				if err := os.MkdirAll(tt.existing, 0o755); err != nil {
+					t.Fatal(err)
+				}
+			}
+			// git has made a start on the clone when it fails.
+			fake := &execx.Fake{Responses: []execx.FakeResponse{{
+				Argv:   []string{"git", "clone"},
+				Stderr: "fatal: the remote end hung up unexpectedly\n",
+				Err:    tt.err,
+				Effect: func(dir string, argv []string) {
+					os.MkdirAll(filepath.Join(argv[len(argv)-1], ".git", "objects"), 0o755)
+				},
+			}}}
+			runner = fake
+
+			var err error
+			out := captureStdout(t, func() { err = doClone(context.Background(), remote, tt.target, false, false) })
+			if (err != nil) != (tt.err != nil) {
+				t.Errorf("doClone returned %v, want %v", err, tt.err)
+			}
+			if tt.err == nil && out != tt.target+"\n" {
+				t.Errorf("doClone printed %q, want the target", out)
+			}
+			if want := [][]string{{"git", "clone", "--", remote, tt.target}}; !reflect.DeepEqual(fake.Calls(), want) {
+				t.Errorf("ran %q, want %q", fake.Calls(), want)
+			}
+			for _, path := range tt.kept {
+				if _, err := os.Stat(path); err != nil {
+					t.Errorf("%v was removed: %v", path, err)
+				}
+			}
+			for _, path := range tt.removed {
+				if _, err := os.Lstat(path); !os.IsNotExist(err) {
+					t.Errorf("failed clone left %v behind", path)
+				}
+			}
+		})
+	}
+}
+
+func TestGitBatchEnv(t *testing.T) {
+	got := gitBatchEnv([]string{"HOME=/home/me"})
+	want := []string{"HOME=/home/me", "GIT_TERMINAL_PROMPT=0", "GIT_SSH_COMMAND=ssh -o BatchMode=yes"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("gitBatchEnv = %q, want %q", got, want)
+	}
+
+	got = gitBatchEnv([]string{"GIT_SSH_COMMAND=ssh -i ~/.ssh/work"})
+	if last := got[len(got)-1]; last != "GIT_SSH_COMMAND=ssh -i ~/.ssh/work -o BatchMode=yes" {
+		t.Errorf("gitBatchEnv set %q; want the user's ssh command in batch mode", last)
+	}
+}
+
+func TestSSHURL(t *testing.T) {
+	tests := map[string]string{
+		"https://github.com/shalomb/gum":     "git@github.com:shalomb/gum.git",
+		"https://github.com/shalomb/gum.git": "git@github.com:shalomb/gum.git",
+		"http://example.com:8080/o/r/":       "ssh://git@example.com:8080/o/r.git",
+		"https://github.com:443/shalomb/gum": "git@github.com:shalomb/gum.git",
+		"http://example.com:80/o/r":          "git@example.com:o/r.git",
+		"ssh://git@example.com:2222/o/r.git": "ssh://git@example.com:2222/o/r.git",
+		"git@github.com:shalomb/gum.git":     "git@github.com:shalomb/gum.git",
+		"file:///srv/git/gum.git":            "file:///srv/git/gum.git",
+		"https://github.com/":                "https://github.com/",
+	}
+	for in, want := range tests {
+		if got := sshURL(in); got != want {
+			t.Errorf("sshURL(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
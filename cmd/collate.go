@@ -0,0 +1,85 @@
+/*
+Copyright © 2023 shalomb <s.bhooshi@gmail.com>
+*/
+package cmd
+
+import (
+	"strings"
+)
+
+// Paths are listed in natural order wherever gum sorts them: letters compare
+// case-insensitively and runs of digits compare by numeric value, so that
+// "Beta" sorts between "alpha" and "gamma" and "project2" comes before
+// "project10". Paths that differ only in case are ordered by byte value so
+// that the order is total and stable across runs.
+//
+// --sort-case-sensitive keeps the numeric handling but compares letters by
+// byte value, i.e. upper case before lower case.
+
+// collateLess reports whether path a sorts before path b.
+func collateLess(a, b string) bool {
+	if c := collate(a, b, !SortCaseSensitive); c != 0 {
+		return c < 0
+	}
+	return a < b
+}
+
+// collate compares a and b in natural order, returning -1, 0 or +1.
+func collate(a, b string, foldCase bool) int {
+	for a != "" && b != "" {
+		var ca, cb string
+		ca, a = nextChunk(a)
+		cb, b = nextChunk(b)
+
+		if isDigit(ca[0]) && isDigit(cb[0]) {
+			if c := compareNumeric(ca, cb); c != 0 {
+				return c
+			}
+			continue
+		}
+
+		if foldCase {
+			ca, cb = strings.ToLower(ca), strings.ToLower(cb)
+		}
+		if c := strings.Compare(ca, cb); c != 0 {
+			return c
+		}
+	}
+	return strings.Compare(a, b)
+}
+
+// nextChunk splits s after its leading run of digits or non-digits.
+func nextChunk(s string) (chunk, rest string) {
+	digits := isDigit(s[0])
+	i := 1
+	for i < len(s) && isDigit(s[i]) == digits {
+		i++
+	}
+	return s[:i], s[i:]
+}
+
+// compareNumeric compares two runs of digits by value without overflowing,
+// falling back to the number of leading zeros so that "01" != "1".
+func compareNumeric(a, b string) int {
+	ta, tb := strings.TrimLeft(a, "0"), strings.TrimLeft(b, "0")
+	if len(ta) != len(tb) {
+		if len(ta) < len(tb) {
+			return -1
+		}
+		return 1
+	}
+	if c := strings.Compare(ta, tb); c != 0 {
+		return c
+	}
+	if len(a) != len(b) {
+		if len(a) < len(b) {
+			return -1
+		}
+		return 1
+	}
+	return 0
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
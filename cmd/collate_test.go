@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestCollateLess(t *testing.T) {
+	tests := []struct {
+		name          string
+		caseSensitive bool
+		paths         []string
+		want          []string
+	}{
+		{
+			name:  "letters ignore case",
+			paths: []string{"/p/gamma", "/p/Beta", "/p/alpha"},
+			want:  []string{"/p/alpha", "/p/Beta", "/p/gamma"},
+		},
+		{
+			name:  "digits by value",
+			paths: []string{"/p/project10", "/p/project2", "/p/project1", "/p/project01"},
+			want:  []string{"/p/project1", "/p/project01", "/p/project2", "/p/project10"},
+		},
+		{
+			name:  "numbers longer than an int",
+			paths: []string{"/p/v100000000000000000000", "/p/v99999999999999999999"},
+			want:  []string{"/p/v99999999999999999999", "/p/v100000000000000000000"},
+		},
+		{
+			name:  "case only as a tie-break",
+			paths: []string{"/p/readme", "/p/README", "/p/Readme"},
+			want:  []string{"/p/README", "/p/Readme", "/p/readme"},
+		},
+		{
+			name:  "prefixes first",
+			paths: []string{"/p/gum-wt/fix", "/p/gum", "/p/gum-wt"},
+			want:  []string{"/p/gum", "/p/gum-wt", "/p/gum-wt/fix"},
+		},
+		{
+			name:          "upper case first when case-sensitive",
+			caseSensitive: true,
+			paths:         []string{"/p/gamma", "/p/Beta", "/p/alpha", "/p/x10", "/p/x9"},
+			want:          []string{"/p/Beta", "/p/alpha", "/p/gamma", "/p/x9", "/p/x10"},
+		},
+	}
+
+	saved := SortCaseSensitive
+	t.Cleanup(func() { SortCaseSensitive = saved })
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			SortCaseSensitive = tt.caseSensitive
+			got := append([]string(nil), tt.paths...)
+			sort.Slice(got, func(i, j int) bool { return collateLess(got[i], got[j]) })
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("sorted %q to %q, want %q", tt.paths, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCollateLessIsStrict(t *testing.T) {
+	paths := []string{"a", "A", "a1", "a01", "a001", "B", "b", ""}
+	for _, a := range paths {
+		if collateLess(a, a) {
+			t.Errorf("collateLess(%q, %q) is true", a, a)
+		}
+		for _, b := range paths {
+			if a != b && collateLess(a, b) == collateLess(b, a) {
+				t.Errorf("collateLess(%q, %q) and collateLess(%q, %q) agree", a, b, b, a)
+			}
+		}
+	}
+}
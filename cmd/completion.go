@@ -0,0 +1,170 @@
+/*
+Copyright © 2023 shalomb <s.bhooshi@gmail.com>
+*/
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/adrg/xdg"
+	"github.com/spf13/cobra"
+)
+
+// completionInstallCmd represents the completion install command
+var completionInstallCmd = &cobra.Command{
+	Use:   "install [bash|zsh|fish]",
+	Short: "Install the completion script for your shell",
+	Long: `Write the completion script for a shell where that shell picks it up,
+so that completion works in new shells. The shell defaults to $SHELL.
+
+  bash  $XDG_DATA_HOME/bash-completion/completions/gum (needs bash-completion 2)
+  zsh   ~/.zsh/completions/_gum (the directory must be on $fpath)
+  fish  $XDG_CONFIG_HOME/fish/completions/gum.fish
+
+With --system the script goes to the system-wide location instead, which
+usually needs root. Without it, gum refuses to write outside your home
+directory. Running it again replaces the script with the current one;
+--uninstall removes it.`,
+	Args:         cobra.MaximumNArgs(1),
+	ValidArgs:    []string{"bash", "zsh", "fish"},
+	SilenceUsage: true,
+
+	RunE: func(cmd *cobra.Command, args []string) error {
+		shell := filepath.Base(os.Getenv("SHELL"))
+		if len(args) > 0 {
+			shell = args[0]
+		}
+		uninstall, _ := cmd.Flags().GetBool("uninstall")
+		system, _ := cmd.Flags().GetBool("system")
+		return doCompletionInstall(cmd.OutOrStdout(), shell, system, uninstall)
+	},
+}
+
+func init() {
+	completionInstallCmd.Flags().Bool("uninstall", false, "Remove the installed completion script")
+	completionInstallCmd.Flags().Bool("system", false, "Install for all users rather than in your home directory")
+}
+
+// addCompletionInstall adds the install command alongside the bash, zsh,
+// fish and powershell script generators of cobra's completion command. It
+// must run once every other command is registered, as cobra only creates
+// the completion command for a root that has subcommands.
+func addCompletionInstall() {
+	rootCmd.InitDefaultCompletionCmd()
+	for _, c := range rootCmd.Commands() {
+		if c.Name() == "completion" {
+			c.AddCommand(completionInstallCmd)
+		}
+	}
+}
+
+// completionTarget is where the completion script for a shell goes.
+type completionTarget struct {
+	// Path is the file the script is written to.
+	Path string
+	// Hint tells the user what else is needed to load the script, if
+	// anything.
+	Hint string
+	// Generate writes the script.
+	Generate func(w io.Writer) error
+}
+
+// completionTargetFor returns where to install the completion script for
+// shell, per user or system-wide.
+func completionTargetFor(shell string, system bool) (completionTarget, error) {
+	switch shell {
+	case "bash":
+		dir := filepath.Join(xdg.DataHome, "bash-completion", "completions")
+		if system {
+			dir = "/usr/share/bash-completion/completions"
+		}
+		return completionTarget{
+			Path: filepath.Join(dir, "gum"),
+			Hint: "bash-completion 2 loads it in new shells; install bash-completion if it does not.",
+			Generate: func(w io.Writer) error {
+				return rootCmd.GenBashCompletionV2(w, true)
+			},
+		}, nil
+	case "zsh":
+		dir := filepath.Join(xdg.Home, ".zsh", "completions")
+		hint := "Add this to ~/.zshrc if it is not there yet:\n\n" +
+			"  fpath=(~/.zsh/completions $fpath)\n  autoload -U compinit && compinit"
+		if system {
+			dir = "/usr/local/share/zsh/site-functions"
+			hint = "Run compinit again, or start a new shell, to load it."
+		}
+		return completionTarget{
+			Path:     filepath.Join(dir, "_gum"),
+			Hint:     hint,
+			Generate: rootCmd.GenZshCompletion,
+		}, nil
+	case "fish":
+		dir := filepath.Join(xdg.ConfigHome, "fish", "completions")
+		if system {
+			dir = "/usr/share/fish/vendor_completions.d"
+		}
+		return completionTarget{
+			Path: filepath.Join(dir, "gum.fish"),
+			Generate: func(w io.Writer) error {
+				return rootCmd.GenFishCompletion(w, true)
+			},
+		}, nil
+	}
+	return completionTarget{}, fmt.Errorf("cannot install completion for shell %q: expected bash, zsh or fish", shell)
+}
+
+// doCompletionInstall installs, or with uninstall removes, the completion
+// script for shell and reports what it did to w.
+func doCompletionInstall(w io.Writer, shell string, system, uninstall bool) error {
+	target, err := completionTargetFor(shell, system)
+	if err != nil {
+		return err
+	}
+
+	if !system && !pathWithin(target.Path, xdg.Home) {
+		return fmt.Errorf("%v is outside your home directory; use --system to install there", target.Path)
+	}
+
+	if uninstall {
+		err := os.Remove(target.Path)
+		if errors.Is(err, fs.ErrNotExist) {
+			fmt.Fprintf(w, "%v is not installed\n", target.Path)
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("error removing %v: %w", target.Path, err)
+		}
+		fmt.Fprintf(w, "Removed %v\n", target.Path)
+		return nil
+	}
+
+	var script bytes.Buffer
+	if err := target.Generate(&script); err != nil {
+		return fmt.Errorf("error generating %v completion: %w", shell, err)
+	}
+
+	if old, err := os.ReadFile(target.Path); err == nil && bytes.Equal(old, script.Bytes()) {
+		fmt.Fprintf(w, "%v is up to date\n", target.Path)
+	} else {
+		if err := os.MkdirAll(filepath.Dir(target.Path), 0o755); err != nil {
+			return fmt.Errorf("error creating %v: %w", filepath.Dir(target.Path), err)
+		}
+		if err := writeFileAtomic(target.Path, script.Bytes(), 0o644); err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "Wrote %v\n", target.Path)
+	}
+
+	// The hint is repeated when the script is up to date, as the shell may
+	// still not be set up to load it.
+	if target.Hint != "" {
+		fmt.Fprintf(w, "%v\n", target.Hint)
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/adrg/xdg"
+)
+
+func TestCompletionInstall(t *testing.T) {
+	tests := []struct {
+		shell string
+		path  string
+		hint  string
+	}{
+		{"bash", ".local/share/bash-completion/completions/gum", "bash-completion 2 loads it"},
+		{"zsh", ".zsh/completions/_gum", "  fpath=(~/.zsh/completions $fpath)\n"},
+		{"fish", ".config/fish/completions/gum.fish", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.shell, func(t *testing.T) {
+			home := withHome(t)
+			path := filepath.Join(home, tt.path)
+
+			var first bytes.Buffer
+			if err := doCompletionInstall(&first, tt.shell, false, false); err != nil {
+				t.Fatalf("install failed: %v", err)
+			}
+			if !strings.HasPrefix(first.String(), "Wrote "+path+"\n") {
+				t.Errorf("install printed %q, want it to report writing %v", first.String(), path)
+			}
+			if !strings.Contains(first.String(), tt.hint) {
+				t.Errorf("install printed %q, want it to contain %q", first.String(), tt.hint)
+			}
+			script, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Contains(script, []byte("gum")) {
+				t.Errorf("%v does not look like a gum completion script", path)
+			}
+
+			// Installing again leaves the script alone and gives the same hint.
+			var second bytes.Buffer
+			if err := doCompletionInstall(&second, tt.shell, false, false); err != nil {
+				t.Fatalf("second install failed: %v", err)
+			}
+			if again, _ := os.ReadFile(path); !bytes.Equal(again, script) {
+				t.Errorf("installing again changed %v", path)
+			}
+			want := path + " is up to date\n" + strings.TrimPrefix(first.String(), "Wrote "+path+"\n")
+			if second.String() != want {
+				t.Errorf("second install printed %q, want %q", second.String(), want)
+			}
+
+			var out bytes.Buffer
+			if err := doCompletionInstall(&out, tt.shell, false, true); err != nil {
+				t.Fatalf("uninstall failed: %v", err)
+			}
+			if out.String() != "Removed "+path+"\n" {
+				t.Errorf("uninstall printed %q", out.String())
+			}
+			if _, err := os.Stat(path); !os.IsNotExist(err) {
+				t.Errorf("%v is still there after uninstall: %v", path, err)
+			}
+
+			out.Reset()
+			if err := doCompletionInstall(&out, tt.shell, false, true); err != nil {
+				t.Fatalf("second uninstall failed: %v", err)
+			}
+			if out.String() != path+" is not installed\n" {
+				t.Errorf("second uninstall printed %q", out.String())
+			}
+		})
+	}
+}
+
+func TestCompletionInstallStaysInHome(t *testing.T) {
+	withHome(t)
+	outside := t.TempDir()
+	t.Setenv("XDG_DATA_HOME", outside)
+	t.Setenv("XDG_CONFIG_HOME", outside)
+	xdg.Reload()
+
+	for _, shell := range []string{"bash", "fish"} {
+		var out bytes.Buffer
+		err := doCompletionInstall(&out, shell, false, false)
+		if err == nil || !strings.Contains(err.Error(), "--system") {
+			t.Errorf("installing %v completion outside home = %v, want an error suggesting --system", shell, err)
+		}
+	}
+	if entries, _ := os.ReadDir(outside); len(entries) > 0 {
+		t.Errorf("install wrote %v outside home", entries[0].Name())
+	}
+
+	var out bytes.Buffer
+	if err := doCompletionInstall(&out, "tcsh", false, false); err == nil {
+		t.Errorf("installing tcsh completion succeeded, want an error")
+	}
+}
@@ -0,0 +1,416 @@
+/*
+Copyright © 2023 shalomb <s.bhooshi@gmail.com>
+*/
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+)
+
+// configCmd represents the config command
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect and change the configuration file",
+}
+
+// configSetCmd represents the config set command
+var configSetCmd = &cobra.Command{
+	Use:   "set <key> <value>",
+	Short: "Set a key in the configuration file",
+	Long: `Set a key in the configuration file, given as a dotted path, to a YAML
+value written on one line:
+
+  gum config set edit.gui '[emacsclient, zed]'
+  gum config set commands.morning '["update", "dirs"]'
+
+Only the text of the value is changed; comments, blank lines, ordering and
+all other keys are left exactly as they were. Missing keys are added at
+the end of their parent mapping. The previous file is kept next to it
+with a .bak suffix and the new one is written atomically.`,
+	Args: cobra.ExactArgs(2),
+
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path := viper.ConfigFileUsed()
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("error reading config: %w", err)
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return fmt.Errorf("error reading config: %w", err)
+		}
+
+		updated, err := setConfigValue(data, args[0], args[1])
+		if err != nil {
+			return err
+		}
+		if bytes.Equal(updated, data) {
+			return nil
+		}
+
+		if err := writeFileAtomic(path+".bak", data, info.Mode().Perm()); err != nil {
+			return err
+		}
+		return writeFileAtomic(path, updated, info.Mode().Perm())
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(configCmd)
+	configCmd.AddCommand(configSetCmd)
+}
+
+// setConfigValue returns the YAML document data with the dotted key set to
+// value, a single line of YAML. Only the bytes of the old value change, or
+// new lines are inserted when the key does not exist yet, so that the rest
+// of a hand-edited file is preserved byte for byte.
+func setConfigValue(data []byte, key, value string) ([]byte, error) {
+	parts := strings.Split(key, ".")
+	for _, p := range parts {
+		if p == "" {
+			return nil, fmt.Errorf("invalid key %q", key)
+		}
+	}
+
+	value = strings.TrimSpace(value)
+	var parsed yaml.Node
+	if strings.ContainsAny(value, "\r\n") || yaml.Unmarshal([]byte(value), &parsed) != nil {
+		return nil, fmt.Errorf("invalid value %q: expected YAML on a single line", value)
+	}
+	if strings.HasPrefix(value, "#") || value == "" {
+		value = `""`
+	}
+
+	updated, err := editConfigValue(data, key, parts, value)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkConfigEdit(data, updated, parts, value); err != nil {
+		return nil, fmt.Errorf("cannot set %v: %w", key, err)
+	}
+	return updated, nil
+}
+
+// editConfigValue does the work of setConfigValue on the source text.
+func editConfigValue(data []byte, key string, parts []string, value string) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("error parsing config: %w", err)
+	}
+	src := newYAMLSource(data)
+	if len(doc.Content) == 0 {
+		return src.insertAfterLine(len(src.lines), nestedYAML(parts, value, 0)), nil
+	}
+
+	mapping := doc.Content[0]
+	for i, part := range parts {
+		if mapping.Kind != yaml.MappingNode {
+			return nil, fmt.Errorf("cannot set %v: %v is not a mapping", key, strings.Join(parts[:i], "."))
+		}
+
+		k, v := lookupYAMLKey(mapping, part)
+		if k == nil {
+			if mapping.Style&yaml.FlowStyle != 0 {
+				return nil, fmt.Errorf("cannot add %v to a mapping written in flow style; edit the config by hand", key)
+			}
+			last, err := src.lastLine(mapping)
+			if err != nil {
+				return nil, fmt.Errorf("cannot add %v: %w", key, err)
+			}
+			return src.insertAfterLine(last, nestedYAML(parts[i:], value, mapping.Content[0].Column-1)), nil
+		}
+
+		if i == len(parts)-1 {
+			start, end, prefix, err := src.extent(k, v)
+			if err != nil {
+				return nil, fmt.Errorf("cannot set %v: %w", key, err)
+			}
+			return src.replace(start, end, prefix+value), nil
+		}
+		mapping = v
+	}
+	return data, nil
+}
+
+// checkConfigEdit makes sure that updated holds the same configuration as
+// data but for the key at parts being set to value, so that a mistake in
+// rewriting the source can never corrupt the file.
+func checkConfigEdit(data, updated []byte, parts []string, value string) error {
+	var want, got, v any
+	if err := yaml.Unmarshal(data, &want); err != nil {
+		return err
+	}
+	if err := yaml.Unmarshal(updated, &got); err != nil {
+		return fmt.Errorf("the edited config would not parse (%v); edit the config by hand", err)
+	}
+	if err := yaml.Unmarshal([]byte(value), &v); err != nil {
+		return err
+	}
+
+	if want == nil {
+		want = map[string]any{}
+	}
+	mapping, _ := want.(map[string]any)
+	for i, part := range parts {
+		if mapping == nil {
+			return fmt.Errorf("%v is not a mapping", strings.Join(parts[:i], "."))
+		}
+		key := part
+		if _, ok := mapping[key]; !ok {
+			for k := range mapping {
+				if strings.EqualFold(k, part) {
+					key = k
+				}
+			}
+		}
+		if i == len(parts)-1 {
+			mapping[key] = v
+			break
+		}
+		next, ok := mapping[key].(map[string]any)
+		if !ok && mapping[key] == nil {
+			next = map[string]any{}
+			mapping[key] = next
+		}
+		mapping = next
+	}
+
+	if !reflect.DeepEqual(want, got) {
+		return fmt.Errorf("the edit would change more than its value; edit the config by hand")
+	}
+	return nil
+}
+
+// lookupYAMLKey finds key in mapping, exactly or else case-insensitively as
+// viper does, and returns its key and value nodes.
+func lookupYAMLKey(mapping *yaml.Node, key string) (*yaml.Node, *yaml.Node) {
+	var k, v *yaml.Node
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i], mapping.Content[i+1]
+		}
+		if k == nil && strings.EqualFold(mapping.Content[i].Value, key) {
+			k, v = mapping.Content[i], mapping.Content[i+1]
+		}
+	}
+	return k, v
+}
+
+// nestedYAML renders "a:\n  b: value\n" for parts a, b, indented by indent
+// spaces.
+func nestedYAML(parts []string, value string, indent int) string {
+	var b strings.Builder
+	for i, part := range parts {
+		b.WriteString(strings.Repeat(" ", indent+2*i))
+		b.WriteString(part)
+		b.WriteString(":")
+		if i == len(parts)-1 {
+			b.WriteString(" " + value)
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// yamlSource maps yaml.Node positions back to byte offsets in a document.
+type yamlSource struct {
+	data  []byte
+	lines []int // byte offset at which each line starts
+}
+
+func newYAMLSource(data []byte) *yamlSource {
+	s := &yamlSource{data: data, lines: []int{0}}
+	for i, c := range data {
+		if c == '\n' {
+			s.lines = append(s.lines, i+1)
+		}
+	}
+	return s
+}
+
+// lastLine returns the last source line spanned by node and its children.
+func (s *yamlSource) lastLine(node *yaml.Node) (int, error) {
+	if node.Kind == yaml.ScalarNode && node.Style&(yaml.LiteralStyle|yaml.FoldedStyle) != 0 {
+		return s.blockScalarEnd(node)
+	}
+	line := node.Line
+	for _, c := range node.Content {
+		l, err := s.lastLine(c)
+		if err != nil {
+			return 0, err
+		}
+		line = max(line, l)
+	}
+	return line, nil
+}
+
+// blockScalarEnd returns the last line of the | or > block scalar node.
+// yaml.v3 only records the line of the indicator, so the block is read
+// from the source: it runs for as long as lines are blank or indented at
+// least as deep as its first line. Trailing blank lines belong to it only
+// when it keeps them with the + indicator.
+func (s *yamlSource) blockScalarEnd(node *yaml.Node) (int, error) {
+	header := s.data[s.offset(node.Line, node.Column):s.lineEnd(node.Line)]
+	if i := bytes.IndexAny(header, " \t"); i >= 0 {
+		header = header[:i]
+	}
+	if bytes.ContainsAny(header, "123456789") {
+		return 0, fmt.Errorf("a value has an explicit indentation indicator; edit the config by hand")
+	}
+	if strings.TrimSpace(node.Value) == "" {
+		return node.Line, nil
+	}
+	keep := bytes.ContainsRune(header, '+')
+
+	last, indent := node.Line, -1
+	for line := node.Line + 1; line <= len(s.lines); line++ {
+		text := s.data[s.lines[line-1]:s.lineEnd(line)]
+		content := bytes.TrimLeft(text, " ")
+		if len(bytes.TrimSpace(content)) == 0 {
+			if keep {
+				last = line
+			}
+			continue
+		}
+		if n := len(text) - len(content); indent < 0 {
+			indent = n
+		} else if n < indent {
+			break
+		}
+		last = line
+	}
+	return last, nil
+}
+
+// offset converts a 1-based line and column, as counted in characters by
+// yaml.v3, to a byte offset.
+func (s *yamlSource) offset(line, column int) int {
+	off := s.lines[line-1]
+	for col := 1; col < column && off < len(s.data); col++ {
+		_, size := utf8.DecodeRune(s.data[off:])
+		off += size
+	}
+	return off
+}
+
+// lineEnd returns the offset of the newline ending line, or the end of the
+// data.
+func (s *yamlSource) lineEnd(line int) int {
+	if line < len(s.lines) {
+		return s.lines[line] - 1
+	}
+	return len(s.data)
+}
+
+// extent returns the byte range of the source text of the value v of key
+// k, and the prefix the replacement needs. Empty values and block
+// collections are replaced from right after the colon, so that whatever
+// replaces them is on the key's line.
+func (s *yamlSource) extent(k, v *yaml.Node) (int, int, string, error) {
+	start := s.offset(v.Line, v.Column)
+	rest := s.data[start:s.lineEnd(v.Line)]
+
+	switch {
+	case v.Kind == yaml.ScalarNode && v.Tag == "!!null" && v.Value == "",
+		v.Style&yaml.FlowStyle == 0 && (v.Kind == yaml.MappingNode || v.Kind == yaml.SequenceNode):
+		colon, err := s.colonAfter(k)
+		if err != nil {
+			return 0, 0, "", err
+		}
+		end := colon
+		if v.Kind != yaml.ScalarNode {
+			last, err := s.lastLine(v)
+			if err != nil {
+				return 0, 0, "", err
+			}
+			end = s.lineEnd(last)
+		}
+		return colon, end, " ", nil
+	case v.Kind == yaml.ScalarNode && v.Style&yaml.DoubleQuotedStyle != 0:
+		for i := 1; i < len(rest); i++ {
+			if rest[i] == '\\' {
+				i++
+			} else if rest[i] == '"' {
+				return start, start + i + 1, "", nil
+			}
+		}
+	case v.Kind == yaml.ScalarNode && v.Style&yaml.SingleQuotedStyle != 0:
+		for i := 1; i < len(rest); i++ {
+			if rest[i] == '\'' {
+				if i+1 < len(rest) && rest[i+1] == '\'' {
+					i++
+					continue
+				}
+				return start, start + i + 1, "", nil
+			}
+		}
+	case v.Kind == yaml.ScalarNode && v.Style&(yaml.LiteralStyle|yaml.FoldedStyle) == 0:
+		if bytes.HasPrefix(rest, []byte(v.Value)) {
+			return start, start + len(v.Value), "", nil
+		}
+	case v.Kind == yaml.AliasNode:
+		return start, start + 1 + len(v.Value), "", nil
+	case v.Style&yaml.FlowStyle != 0:
+		depth, quote := 0, byte(0)
+		for i, c := range rest {
+			switch {
+			case quote != 0:
+				if c == quote {
+					quote = 0
+				}
+			case c == '"' || c == '\'':
+				quote = c
+			case c == '[' || c == '{':
+				depth++
+			case c == ']' || c == '}':
+				if depth--; depth == 0 {
+					return start, start + i + 1, "", nil
+				}
+			}
+		}
+	}
+
+	return 0, 0, "", fmt.Errorf("its value spans several lines; edit the config by hand")
+}
+
+// colonAfter returns the offset just past the colon following key k.
+func (s *yamlSource) colonAfter(k *yaml.Node) (int, error) {
+	_, end, _, err := s.extent(nil, k)
+	if err != nil {
+		return 0, err
+	}
+	colon := bytes.IndexByte(s.data[end:s.lineEnd(k.Line)], ':')
+	if colon < 0 {
+		return 0, fmt.Errorf("its key spans several lines; edit the config by hand")
+	}
+	return end + colon + 1, nil
+}
+
+// replace returns the data with the bytes from start to end replaced.
+func (s *yamlSource) replace(start, end int, text string) []byte {
+	out := append([]byte{}, s.data[:start]...)
+	out = append(out, text...)
+	return append(out, s.data[end:]...)
+}
+
+// insertAfterLine returns the data with text inserted after line.
+func (s *yamlSource) insertAfterLine(line int, text string) []byte {
+	at := s.lineEnd(line)
+	if at == len(s.data) {
+		if len(s.data) > 0 && s.data[len(s.data)-1] != '\n' {
+			text = "\n" + text
+		}
+		return s.replace(at, at, text)
+	}
+	return s.replace(at+1, at+1, text)
+}
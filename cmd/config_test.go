@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSetConfigValue(t *testing.T) {
+	tests := []struct {
+		name       string
+		data       string
+		key, value string
+		want       string
+	}{
+		{
+			name: "replaces a value keeping comments",
+			data: "# gum config\nprojects:\n  - ~/projects # mine\nedit:\n  gui: [zed] # editors\n",
+			key:  "edit.gui", value: "[emacsclient, zed]",
+			want: "# gum config\nprojects:\n  - ~/projects # mine\nedit:\n  gui: [emacsclient, zed] # editors\n",
+		},
+		{
+			name: "adds a key at the end of its mapping",
+			data: "edit:\n  gui: [zed]\n\n# the rest\nprojects: []\n",
+			key:  "edit.newkey", value: "yes",
+			want: "edit:\n  gui: [zed]\n  newkey: yes\n\n# the rest\nprojects: []\n",
+		},
+		{
+			name: "adds nested keys to an empty file",
+			data: "",
+			key:  "discovery.max_depth", value: "3",
+			want: "discovery:\n  max_depth: 3\n",
+		},
+		{
+			name: "adds a key after a literal block",
+			data: "edit:\n  note: |\n    line one\n    line two\nprojects: []\n",
+			key:  "edit.newkey", value: "yes",
+			want: "edit:\n  note: |\n    line one\n    line two\n  newkey: yes\nprojects: []\n",
+		},
+		{
+			name: "adds a key after a block at the end of the file",
+			data: "edit:\n  note: >-\n    line one\n\n    line two\n",
+			key:  "edit.newkey", value: "yes",
+			want: "edit:\n  note: >-\n    line one\n\n    line two\n  newkey: yes\n",
+		},
+		{
+			name: "leaves blank lines after a clipped block outside it",
+			data: "edit:\n  note: |\n    line one\n\n# comment\nprojects: []\n",
+			key:  "edit.newkey", value: "yes",
+			want: "edit:\n  note: |\n    line one\n  newkey: yes\n\n# comment\nprojects: []\n",
+		},
+		{
+			name: "keeps the trailing blank lines of a kept block in it",
+			data: "edit:\n  note: |+\n    line one\n\nprojects: []\n",
+			key:  "edit.newkey", value: "yes",
+			want: "edit:\n  note: |+\n    line one\n\n  newkey: yes\nprojects: []\n",
+		},
+		{
+			name: "replaces a block mapping ending in a block",
+			data: "edit:\n  note: |\n    line one\n    line two\nprojects: []\n",
+			key:  "edit", value: "{gui: [zed]}",
+			want: "edit: {gui: [zed]}\nprojects: []\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := setConfigValue([]byte(tt.data), tt.key, tt.value)
+			if err != nil {
+				t.Fatalf("setConfigValue(%q, %q) failed: %v", tt.key, tt.value, err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("setConfigValue(%q, %q) =\n%s\nwant\n%s", tt.key, tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetConfigValueRefuses(t *testing.T) {
+	tests := []struct {
+		name       string
+		data       string
+		key, value string
+		err        string
+	}{
+		{
+			name: "multi-line value",
+			data: "edit:\n  note: |\n    line one\n",
+			key:  "edit.note", value: "short",
+			err: "spans several lines",
+		},
+		{
+			name: "explicit indentation indicator",
+			data: "edit:\n  note: |2\n     line one\n    line two\n",
+			key:  "edit.newkey", value: "yes",
+			err: "indentation indicator",
+		},
+		{
+			name: "value on several lines",
+			data: "edit: {}\n",
+			key:  "edit.gui", value: "[a,\nb]",
+			err: "single line",
+		},
+		{
+			name: "flow mapping",
+			data: "edit: {gui: [zed]}\n",
+			key:  "edit.newkey", value: "yes",
+			err: "flow style",
+		},
+		{
+			name: "not a mapping",
+			data: "edit: zed\n",
+			key:  "edit.gui", value: "yes",
+			err: "edit is not a mapping",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := setConfigValue([]byte(tt.data), tt.key, tt.value)
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("setConfigValue(%q, %q) = %q, %v; want an error containing %q", tt.key, tt.value, got, err, tt.err)
+			}
+		})
+	}
+}
+
+func TestCheckConfigEdit(t *testing.T) {
+	data := []byte("edit:\n  note: |\n    line one\n")
+	corrupted := []byte("edit:\n  note: |\n  newkey: yes\n    line one\n")
+	if err := checkConfigEdit(data, corrupted, []string{"edit", "newkey"}, "yes"); err == nil {
+		t.Errorf("checkConfigEdit accepted an edit that changed another value")
+	}
+	good := []byte("edit:\n  note: |\n    line one\n  newkey: yes\n")
+	if err := checkConfigEdit(data, good, []string{"edit", "newkey"}, "yes"); err != nil {
+		t.Errorf("checkConfigEdit rejected a good edit: %v", err)
+	}
+}
@@ -4,29 +4,58 @@ Copyright © 2023 NAME HERE <EMAIL ADDRESS>
 package cmd
 
 import (
+	"bufio"
+	"context"
 	"fmt"
 	"os"
+	"path/filepath"
 	"sort"
 	"time"
 
-	ps "github.com/mitchellh/go-ps"
+	"github.com/shalomb/gum/internal/procdirs"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 )
 
 // dirsCmd represents the dirs command
 var dirsCmd = &cobra.Command{
 	Use:   "dirs",
-	Short: "A brief description of your command",
-	Long: `A longer description that spans multiple lines and likely contains examples
-and usage of using your command. For example:
+	Short: "List the directories running processes work in, most frequent first",
+	Long: `List the working directories of the running processes, one
+"frequency<TAB>path" line each, most frequently seen first and most
+recently seen first among equals. The frequency is the number of
+processes seen working in the directory.
 
-Cobra is a CLI library for Go that empowers applications.
-This application is a tool to generate the needed files
-to quickly create a Cobra application.`,
+  cd "$(gum dirs -f null | fzf --read0)"
 
-	Run: func(cmd *cobra.Command, args []string) {
-		doUpdateDirs()
+Processes working anywhere inside a project are counted for the project
+itself, so that a project stands out rather than one of its
+subdirectories. That needs the projects to be found, as by gum projects;
+set dirs.attribute_to_projects to false to list directories as they were
+seen, and skip the search.
+
+--annotate adds a field marking a project [repo] and a directory inside
+one [in:name]. --repos-only lists only the directories that are, or are
+in, a project, and --non-repos-only only the rest.
+
+--format json prints path, frequency, last_seen, subdir (the directory of
+a project seen most often) and project for each entry, and --format null,
+like -0, only the paths, each terminated by a NUL byte.`,
+
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var opts dirsOptions
+		opts.Print0, _ = cmd.Flags().GetBool("print0")
+		opts.Format, _ = cmd.Flags().GetString("format")
+		opts.Pretty, _ = cmd.Flags().GetBool("pretty")
+		opts.Annotate, _ = cmd.Flags().GetBool("annotate")
+		if reposOnly, _ := cmd.Flags().GetBool("repos-only"); reposOnly {
+			opts.Only = "repos"
+		}
+		if nonReposOnly, _ := cmd.Flags().GetBool("non-repos-only"); nonReposOnly {
+			opts.Only = "non-repos"
+		}
+		return doUpdateDirs(cmd.Context(), opts)
 	},
 }
 
@@ -41,48 +70,287 @@ func init() {
 
 	// Cobra supports local flags which will only run when this command
 	// is called directly, e.g.:
-	// dirsCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+	dirsCmd.Flags().BoolP("print0", "0", false, "Print only the paths, each terminated by a NUL byte (for xargs -0, fzf --read0)")
+	dirsCmd.Flags().StringP("format", "f", "default", "Output format (default, json, null)")
+	dirsCmd.Flags().Bool("pretty", false, "Indent JSON output")
+	dirsCmd.Flags().Bool("annotate", false, "Mark entries that are a project [repo] or in one [in:name] (also dirs.annotate)")
+	dirsCmd.Flags().Bool("repos-only", false, "Only list entries that are, or are in, a project")
+	dirsCmd.Flags().Bool("non-repos-only", false, "Only list entries outside every project")
+	dirsCmd.MarkFlagsMutuallyExclusive("repos-only", "non-repos-only")
+
+	viper.SetDefault("dirs.attribute_to_projects", true)
+}
+
+// dirsOptions controls how gum dirs prints its listing.
+type dirsOptions struct {
+	// Print0 prints bare paths terminated by NUL instead of lines.
+	Print0 bool
+	// Format is "default", "json" or "null", which is the same as Print0.
+	Format string
+	// Pretty indents JSON output.
+	Pretty bool
+	// Annotate marks the entries that are, or are in, a project.
+	Annotate bool
+	// Only is "repos" or "non-repos" to list only the entries that are,
+	// or are not, in a project, or empty for all.
+	Only string
+}
+
+// dirJSON is the JSON representation of a dirs entry. Its fields are part
+// of gum's output contract: add to it, but do not rename or remove.
+type dirJSON struct {
+	Path      string `json:"path"`
+	Frequency int64  `json:"frequency"`
+	LastSeen  string `json:"last_seen"`
+	Subdir    string `json:"subdir"`
+	Project   string `json:"project"`
+}
+
+// dirEntry is what gum knows about a directory that processes have been
+// seen working in.
+type dirEntry struct {
+	Path      string
+	Frequency int64
+	LastSeen  time.Time
+	// Subdir is the subdirectory of a project seen most often in the
+	// latest sample, when sightings there were counted for the project.
+	Subdir string
+	// Project is the project the directory is, or is in, if any.
+	Project string
+}
+
+// doUpdateDirs lists directories in use, most frequently seen first. It runs
+// in explicit steps so that each sighting is counted exactly once:
+//
+//  1. load the historical entries (none are persisted yet, so this is empty)
+//  2. sample the working directories of the running processes, counting
+//     sightings inside a project for the project itself unless the
+//     dirs.attribute_to_projects setting is false
+//  3. merge the sightings into the historical entries, once
+//  4. note the project each entry is in, and filter by it
+//  5. render the result
+func doUpdateDirs(ctx context.Context, opts dirsOptions) error {
+	switch opts.Format {
+	case "default", "json":
+	case "null":
+		opts.Format, opts.Print0 = "default", true
+	default:
+		return fmt.Errorf("invalid --format %q: expected default, json or null", opts.Format)
+	}
+	if opts.Format != "default" && opts.Print0 {
+		return fmt.Errorf("--format %v cannot be combined with --print0", opts.Format)
+	}
+
+	// Entries are not persisted between runs yet, so there is no history
+	// to load and the listing is of this one sample. Loading it belongs
+	// here once they are.
+	historical := map[string]dirEntry{}
+	sightings := sampleDirs(ctx)
+
+	attribute := viper.GetBool("dirs.attribute_to_projects")
+	opts.Annotate = opts.Annotate || viper.GetBool("dirs.annotate")
+	var projects []string
+	if attribute || opts.Annotate || opts.Only != "" {
+		var err error
+		if projects, err = dirsProjects(ctx, opts.Only != ""); err != nil {
+			return err
+		}
+	}
+
+	var subdirs map[string]string
+	if attribute {
+		sightings, subdirs = attributeSightings(sightings, projects)
+	}
+
+	dirs := mergeSightings(historical, sightings, time.Now())
+	for path, entry := range dirs {
+		entry.Subdir = subdirs[path]
+		entry.Project = enclosingProject(path, projects)
+		if opts.Only == "repos" && entry.Project == "" || opts.Only == "non-repos" && entry.Project != "" {
+			delete(dirs, path)
+			continue
+		}
+		dirs[path] = entry
+	}
+	return renderDirs(dirs, opts)
 }
 
-func doUpdateDirs() {
-	pslist, err := ps.Processes()
+// dirsProjects returns the projects to attribute and annotate directories
+// with, symlinks resolved. If they cannot be found, that is an error when
+// required, as for --repos-only; otherwise the directories are listed as
+// they were seen, with a warning.
+func dirsProjects(ctx context.Context, required bool) ([]string, error) {
+	found, err := findProjects(ctx, runner)
 	if err != nil {
-		log.Printf("error listing processes")
+		if required || ctx.Err() != nil {
+			return nil, err
+		}
+		log.Warnf("not attributing directories to projects: %v", err)
+		return nil, nil
 	}
 
-	dirs := make(map[string]int64)
+	// Working directories come from the kernel with symlinks resolved, so
+	// compare the projects in that form too.
+	var projects []string
+	for _, p := range found {
+		if real, err := filepath.EvalSymlinks(p); err == nil {
+			p = real
+		}
+		projects = append(projects, p)
+	}
+	return projects, nil
+}
 
-	for _, pid := range pslist {
-		// log.Printf("%d\t%s\t%+v\n", pid.Pid(), pid.Executable(), pid)
-		cwd := fmt.Sprintf("/proc/%d/cwd", pid.Pid())
+// sampleDirs returns the number of running processes whose working directory
+// is each directory, excluding gum's own directories. Where processes cannot
+// be inspected it returns no sightings, leaving only historical entries.
+func sampleDirs(ctx context.Context) map[string]int64 {
+	sightings := make(map[string]int64)
 
-		dir, _ := os.Readlink(cwd)
-		// if err != nil {
-			// log.Printf("error reading cwd for pid: %d", pid)
-		// }
+	dirs, err := procdirs.Cwds(ctx, runner)
+	if err != nil {
+		log.Printf("error sampling working directories: %v", err)
+		return sightings
+	}
 
-		if len(dir) > 0 {
-			now := time.Now()
-			var newval int64 = 1
-			if val, ok := dirs[dir]; ok {
-				newval = int64(val)
-			}
-			log.Printf("%v \t -> %v | %v\t-> %v\n", dirs[dir], now.UnixNano(), newval, dir)
-			dirs[dir] = (now.UnixNano() - int64(newval))
-			log.Printf("%v \t -> %v | %v\t-> %v\n", dirs[dir], now.UnixNano(), newval, dir)
+	for _, dir := range dirs {
+		if !isSelfPath(dir) {
+			sightings[dir]++
 		}
 	}
 
-	keys := make([]string, 0, len(dirs))
-	for key := range dirs {
-		keys = append(keys, key)
+	return sightings
+}
+
+// attributeSightings counts the sightings in a directory inside a project
+// for the project root instead, so that working deep in a project makes
+// the project stand out rather than one of its subdirectories. It also
+// returns the subdirectory seen most often in each such project.
+func attributeSightings(sightings map[string]int64, projects []string) (map[string]int64, map[string]string) {
+	attributed := make(map[string]int64, len(sightings))
+	subdirs := map[string]string{}
+	for path, count := range sightings {
+		root := enclosingProject(path, projects)
+		if root == "" || root == path {
+			attributed[path] += count
+			continue
+		}
+
+		attributed[root] += count
+		if best, ok := subdirs[root]; !ok || count > sightings[best] ||
+			count == sightings[best] && collateLess(path, best) {
+			subdirs[root] = path
+		}
+	}
+	return attributed, subdirs
+}
+
+// mergeSightings folds one sample of sightings into the historical entries
+// and returns the merged set; historical is not modified.
+//
+// Every process seen in a directory counts as one sighting, so a
+// directory's frequency is the total number of sightings over all samples
+// merged so far, and its last seen time is that of the latest sample it
+// appeared in. Each sample must be merged exactly once.
+func mergeSightings(historical map[string]dirEntry, sightings map[string]int64, now time.Time) map[string]dirEntry {
+	merged := make(map[string]dirEntry, len(historical)+len(sightings))
+	for path, entry := range historical {
+		merged[path] = entry
+	}
+
+	for path, count := range sightings {
+		entry := merged[path]
+		entry.Path = path
+		entry.Frequency += count
+		entry.LastSeen = now
+		merged[path] = entry
+	}
+
+	return merged
+}
+
+// dirAnnotation returns "[repo]" for an entry that is a project, "[in:name]"
+// for one inside project name, and nothing otherwise.
+func dirAnnotation(e dirEntry) string {
+	switch e.Project {
+	case "":
+		return ""
+	case e.Path:
+		return "[repo]"
+	}
+	return "[in:" + filepath.Base(e.Project) + "]"
+}
+
+// renderDirs prints the entries as "frequency<TAB>path" lines, with an
+// annotation field if opts.Annotate, as JSON or
+// as bare NUL-terminated paths, most frequent first and most recently seen
+// first among equals.
+func renderDirs(dirs map[string]dirEntry, opts dirsOptions) error {
+	entries := make([]dirEntry, 0, len(dirs))
+	for _, entry := range dirs {
+		entries = append(entries, entry)
 	}
-	sort.Slice(keys,
+	sort.Slice(entries,
 		func(i, j int) bool {
-			return dirs[keys[i]] > dirs[keys[j]]
+			if entries[i].Frequency != entries[j].Frequency {
+				return entries[i].Frequency > entries[j].Frequency
+			}
+			if !entries[i].LastSeen.Equal(entries[j].LastSeen) {
+				return entries[i].LastSeen.After(entries[j].LastSeen)
+			}
+			return collateLess(entries[i].Path, entries[j].Path)
 		})
 
-	for _, key := range keys {
-		fmt.Printf("%v\t%v\n", dirs[key], key)
+	// dirs can list thousands of entries; write them in blocks.
+	w := bufio.NewWriter(os.Stdout)
+	defer w.Flush()
+
+	if opts.Format == "json" {
+		out := make([]dirJSON, 0, len(entries))
+		for _, entry := range entries {
+			out = append(out, dirJSON{
+				Path:      entry.Path,
+				Frequency: entry.Frequency,
+				LastSeen:  entry.LastSeen.UTC().Format(time.RFC3339),
+				Subdir:    entry.Subdir,
+				Project:   entry.Project,
+			})
+		}
+		if err := writeJSON(w, out, opts.Pretty); err != nil {
+			return err
+		}
+		return w.Flush()
+	}
+
+	if opts.Print0 {
+		for _, entry := range entries {
+			fmt.Fprint(w, entry.Path, "\x00")
+		}
+		return w.Flush()
+	}
+
+	width := outputWidth()
+	for _, entry := range entries {
+		var annotation string
+		if opts.Annotate {
+			annotation = dirAnnotation(entry)
+		}
+		if width > 0 {
+			entry.Path = truncateMiddle(entry.Path, dirPathWidth(width, entry.Frequency, annotation))
+		}
+		fmt.Fprintln(w, formatDirLine(entry, annotation))
+	}
+	return w.Flush()
+}
+
+// dirPathWidth returns how long a path may be for its dirs line, with
+// the frequency and annotation, to fit in width columns.
+func dirPathWidth(width int, frequency int64, annotation string) int {
+	start := tabStop(fmt.Sprint(frequency))
+	if annotation == "" {
+		return width - start
 	}
+	// The annotation starts at the tab stop after the path, and that must
+	// leave room for it.
+	return (width-len([]rune(annotation)))/8*8 - 1 - start
 }
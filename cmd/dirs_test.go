@@ -0,0 +1,395 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shalomb/gum/internal/execx"
+	"github.com/spf13/viper"
+)
+
+func TestDirsProjectsFallsBack(t *testing.T) {
+	withoutLocate(t)
+	viper.Set("projects", []string{"~/gum-test-projects"})
+	saved := runner
+	t.Cleanup(func() {
+		viper.Set("projects", nil)
+		runner = saved
+	})
+	runner = &execx.Fake{Responses: []execx.FakeResponse{{Argv: []string{"find"}, Err: exitError(t, 2)}}}
+
+	if projects, err := dirsProjects(context.Background(), false); projects != nil || err != nil {
+		t.Errorf("dirsProjects = %q, %v; want no projects and no error", projects, err)
+	}
+	if _, err := dirsProjects(context.Background(), true); err == nil {
+		t.Errorf("dirsProjects succeeded when projects are required and cannot be found")
+	}
+}
+
+func TestAttributeSightings(t *testing.T) {
+	projects := []string{"/p/foo", "/p/foo/vendor/bar"}
+	sightings := map[string]int64{
+		"/p/foo":                  1,
+		"/p/foo/src/deep/pkg":     5,
+		"/p/foo/docs":             2,
+		"/p/foo/vendor/bar/x":     3,
+		"/home/me/downloads":      4,
+		"/p/foothold/not-project": 1,
+	}
+
+	attributed, subdirs := attributeSightings(sightings, projects)
+
+	want := map[string]int64{
+		"/p/foo":                  8,
+		"/p/foo/vendor/bar":       3,
+		"/home/me/downloads":      4,
+		"/p/foothold/not-project": 1,
+	}
+	if !reflect.DeepEqual(attributed, want) {
+		t.Errorf("attributeSightings counted %v, want %v", attributed, want)
+	}
+	wantSubdirs := map[string]string{"/p/foo": "/p/foo/src/deep/pkg", "/p/foo/vendor/bar": "/p/foo/vendor/bar/x"}
+	if !reflect.DeepEqual(subdirs, wantSubdirs) {
+		t.Errorf("attributeSightings subdirs = %v, want %v", subdirs, wantSubdirs)
+	}
+}
+
+func TestMergeSightingsAccumulates(t *testing.T) {
+	then := time.Date(2023, 5, 1, 9, 0, 0, 0, time.UTC)
+	now := then.Add(time.Hour)
+	historical := map[string]dirEntry{
+		"/p/foo":   {Path: "/p/foo", Frequency: 10, LastSeen: then},
+		"/p/other": {Path: "/p/other", Frequency: 3, LastSeen: then},
+	}
+
+	// The sightings in the project are counted for its root once.
+	attributed, _ := attributeSightings(map[string]int64{"/p/foo/src": 2, "/p/foo/docs": 1}, []string{"/p/foo"})
+	merged := mergeSightings(historical, attributed, now)
+
+	want := map[string]dirEntry{
+		"/p/foo":   {Path: "/p/foo", Frequency: 13, LastSeen: now},
+		"/p/other": {Path: "/p/other", Frequency: 3, LastSeen: then},
+	}
+	if !reflect.DeepEqual(merged, want) {
+		t.Errorf("mergeSightings = %v, want %v", merged, want)
+	}
+}
+
+func TestMergeSightings(t *testing.T) {
+	t0 := time.Date(2023, 5, 1, 9, 0, 0, 0, time.UTC)
+	t1, t2 := t0.Add(time.Minute), t0.Add(2*time.Minute)
+
+	tests := []struct {
+		name       string
+		historical map[string]dirEntry
+		// samples are merged in turn, the first at t1 and the second at t2.
+		samples []map[string]int64
+		want    map[string]dirEntry
+	}{
+		{
+			name:       "cold start",
+			historical: map[string]dirEntry{},
+			samples:    []map[string]int64{{"/a": 2, "/b": 1}},
+			want: map[string]dirEntry{
+				"/a": {Path: "/a", Frequency: 2, LastSeen: t1},
+				"/b": {Path: "/b", Frequency: 1, LastSeen: t1},
+			},
+		},
+		{
+			name:       "cold start without sightings",
+			historical: nil,
+			samples:    []map[string]int64{{}},
+			want:       map[string]dirEntry{},
+		},
+		{
+			name:       "warm",
+			historical: map[string]dirEntry{"/a": {Path: "/a", Frequency: 5, LastSeen: t0}, "/c": {Path: "/c", Frequency: 1, LastSeen: t0}},
+			samples:    []map[string]int64{{"/a": 2}},
+			want: map[string]dirEntry{
+				"/a": {Path: "/a", Frequency: 7, LastSeen: t1},
+				"/c": {Path: "/c", Frequency: 1, LastSeen: t0},
+			},
+		},
+		{
+			name:       "refresh after new sightings",
+			historical: map[string]dirEntry{"/a": {Path: "/a", Frequency: 5, LastSeen: t0}},
+			samples:    []map[string]int64{{"/a": 1, "/b": 1}, {"/b": 2, "/c": 1}},
+			want: map[string]dirEntry{
+				"/a": {Path: "/a", Frequency: 6, LastSeen: t1},
+				"/b": {Path: "/b", Frequency: 3, LastSeen: t2},
+				"/c": {Path: "/c", Frequency: 1, LastSeen: t2},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := fmt.Sprint(tt.historical)
+			merge := func() map[string]dirEntry {
+				dirs := tt.historical
+				for i, sample := range tt.samples {
+					dirs = mergeSightings(dirs, sample, t1.Add(time.Duration(i)*time.Minute))
+				}
+				return dirs
+			}
+
+			if got := merge(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("merged %v, want %v", got, tt.want)
+			}
+			if after := fmt.Sprint(tt.historical); after != before {
+				t.Errorf("mergeSightings changed the historical entries from %v to %v", before, after)
+			}
+			// Merging the same samples into the same history again counts
+			// them once, not twice.
+			if got := merge(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("merging again gave %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// runGum runs gum with args and returns what it printed. Flags set by an
+// earlier run are reset first.
+func runGum(t *testing.T, args ...string) string {
+	t.Helper()
+	t.Cleanup(func() {
+		resetFlags(rootCmd)
+		rootCmd.SetArgs(nil)
+	})
+	resetFlags(rootCmd)
+	rootCmd.SetArgs(args)
+	return captureStdout(t, func() {
+		if _, err := rootCmd.ExecuteC(); err != nil {
+			t.Errorf("gum %q failed: %v", args, err)
+		}
+	})
+}
+
+// captureStdout returns what f writes to os.Stdout.
+func captureStdout(t *testing.T, f func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	saved := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = saved }()
+
+	out := make(chan []byte)
+	go func() {
+		data, _ := io.ReadAll(r)
+		out <- data
+	}()
+	f()
+	w.Close()
+	return string(<-out)
+}
+
+// expandTabs returns line as a terminal with tab stops every 8 columns
+// shows it.
+func expandTabs(line string) string {
+	var b strings.Builder
+	col := 0
+	for _, r := range line {
+		if r == '\t' {
+			b.WriteByte(' ')
+			for col++; col%8 != 0; col++ {
+				b.WriteByte(' ')
+			}
+			continue
+		}
+		b.WriteRune(r)
+		col++
+	}
+	return b.String()
+}
+
+func TestRenderDirsFitsWidth(t *testing.T) {
+	saved := Width
+	t.Cleanup(func() { Width = saved })
+	Width = 40
+
+	long := "/home/me/src/github.com/someone/a-long-project-name"
+	dirs := map[string]dirEntry{
+		long:                 {Path: long, Frequency: 12, Project: long},
+		long + "/docs/notes": {Path: long + "/docs/notes", Frequency: 3, Project: "/p/foo"},
+		"/tmp":               {Path: "/tmp", Frequency: 1},
+	}
+
+	for _, annotate := range []bool{false, true} {
+		out := captureStdout(t, func() {
+			if err := renderDirs(dirs, dirsOptions{Format: "default", Annotate: annotate}); err != nil {
+				t.Errorf("renderDirs failed: %v", err)
+			}
+		})
+		lines := strings.Split(strings.TrimSuffix(out, "\n"), "\n")
+		if len(lines) != 3 {
+			t.Fatalf("renderDirs (annotate=%v) printed %q, want 3 lines", annotate, out)
+		}
+		for i, line := range lines {
+			n := len([]rune(expandTabs(line)))
+			if n > Width {
+				t.Errorf("annotate=%v: line %q is %d columns wide, more than %d", annotate, line, n, Width)
+			}
+			// The long paths fill the line, rather than stopping short of it.
+			if i < 2 && n < Width-2 {
+				t.Errorf("annotate=%v: line %q is only %d of %d columns wide", annotate, line, n, Width)
+			}
+		}
+		if got := strings.Count(out, "…"); got != 2 {
+			t.Errorf("annotate=%v: %d paths truncated, want 2 in\n%s", annotate, got, out)
+		}
+		if got := strings.Contains(out, "[repo]"); got != annotate {
+			t.Errorf("annotate=%v: output has [repo]: %v\n%s", annotate, got, out)
+		}
+	}
+}
+
+func TestDirPathWidth(t *testing.T) {
+	tests := []struct {
+		width      int
+		frequency  int64
+		annotation string
+		want       int
+	}{
+		{40, 12, "", 32},
+		{40, 123456789, "", 24},
+		{40, 12, "[repo]", 23},
+		{40, 12, "[in:foo]", 23},
+		{40, 12, "[in:project]", 15},
+		{10, 12, "[in:project]", -9},
+	}
+	for _, tt := range tests {
+		if got := dirPathWidth(tt.width, tt.frequency, tt.annotation); got != tt.want {
+			t.Errorf("dirPathWidth(%d, %d, %q) = %d, want %d", tt.width, tt.frequency, tt.annotation, got, tt.want)
+		}
+	}
+}
+
+// checkRecords checks that out is exactly the NUL-terminated records want,
+// with nothing added to them, such as newlines.
+func checkRecords(t *testing.T, out string, want []string) {
+	t.Helper()
+	if !strings.HasSuffix(out, "\x00") || strings.HasSuffix(out, "\n") {
+		t.Errorf("output %q does not end with a NUL byte, or ends with a newline", out)
+	}
+	got := strings.Split(strings.TrimSuffix(out, "\x00"), "\x00")
+	for _, record := range got {
+		if strings.Count(record, "\n") != strings.Count(filepath.Base(record), "\n") {
+			t.Errorf("record %q has a newline outside its name", record)
+		}
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("records %q, want %q", got, want)
+	}
+}
+
+func TestDirsPrint0(t *testing.T) {
+	home := withHome(t)
+	withoutLocate(t)
+	withProjects(t, "~/src")
+	project := filepath.Join(home, "src", "my project")
+	inUse := []string{
+		filepath.Join(home, "with space"),
+		filepath.Join(home, "new\nline"),
+		filepath.Join(project, "sub dir"),
+	}
+	for _, dir := range append(inUse, filepath.Join(project, ".git")) {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, dir := range inUse {
+		workIn(t, dir)
+	}
+
+	for _, args := range [][]string{{"--print0"}, {"-0"}, {"--format", "null"}, {"-f", "null"}} {
+		out := runGum(t, append([]string{"dirs"}, args...)...)
+
+		// Other processes may work anywhere, so keep only the fixture.
+		var got []string
+		for _, record := range strings.SplitAfter(out, "\x00") {
+			if strings.HasPrefix(record, home) {
+				got = append(got, record)
+			}
+		}
+		sort.Strings(got)
+		want := []string{inUse[1], project, inUse[0]}
+		checkRecords(t, strings.Join(got, ""), want)
+		if strings.Contains(out, "\t") {
+			t.Errorf("gum dirs %q printed frequencies in %q", args, out)
+		}
+	}
+}
+
+func TestDirsAnnotateAndFilter(t *testing.T) {
+	home := withHome(t)
+	withoutLocate(t)
+	withProjects(t, "~/src")
+	t.Cleanup(func() { viper.Set("dirs.attribute_to_projects", nil) })
+	project := filepath.Join(home, "src", "app")
+	nested := filepath.Join(project, "internal", "pkg")
+	unrelated := filepath.Join(home, "downloads")
+	for _, dir := range []string{filepath.Join(project, ".git"), nested, unrelated} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, dir := range []string{project, nested, nested, unrelated} {
+		workIn(t, dir)
+	}
+
+	tests := []struct {
+		name      string
+		attribute bool
+		opts      dirsOptions
+		want      []string
+	}{
+		{"annotated", false, dirsOptions{Annotate: true},
+			[]string{"2\t" + nested + "\t[in:app]", "1\t" + unrelated, "1\t" + project + "\t[repo]"}},
+		{"annotated and attributed", true, dirsOptions{Annotate: true},
+			[]string{"3\t" + project + "\t[repo]", "1\t" + unrelated}},
+		{"repos only", false, dirsOptions{Only: "repos"},
+			[]string{"2\t" + nested, "1\t" + project}},
+		{"repos only, annotated", false, dirsOptions{Only: "repos", Annotate: true},
+			[]string{"2\t" + nested + "\t[in:app]", "1\t" + project + "\t[repo]"}},
+		{"repos only, attributed", true, dirsOptions{Only: "repos"},
+			[]string{"3\t" + project}},
+		{"non-repos only", false, dirsOptions{Only: "non-repos"},
+			[]string{"1\t" + unrelated}},
+		{"non-repos only, annotated", true, dirsOptions{Only: "non-repos", Annotate: true},
+			[]string{"1\t" + unrelated}},
+		{"plain", false, dirsOptions{},
+			[]string{"2\t" + nested, "1\t" + unrelated, "1\t" + project}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			viper.Set("dirs.attribute_to_projects", tt.attribute)
+			tt.opts.Format = "default"
+			out := captureStdout(t, func() {
+				if err := doUpdateDirs(context.Background(), tt.opts); err != nil {
+					t.Errorf("doUpdateDirs failed: %v", err)
+				}
+			})
+
+			// Other processes may work anywhere, so keep only the fixture.
+			var got []string
+			for _, line := range strings.Split(strings.TrimSuffix(out, "\n"), "\n") {
+				if strings.Contains(line, home) {
+					got = append(got, line)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("gum dirs printed\n%q\nwant\n%q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+/*
+Copyright © 2023 shalomb <s.bhooshi@gmail.com>
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// guiEditors are started in the background rather than attached to the
+// terminal. More can be added with the edit.gui config setting.
+var guiEditors = []string{"atom", "code", "codium", "goland", "gvim", "idea", "mvim", "pycharm", "subl", "zed"}
+
+// editorRule selects the editor for projects under a path prefix.
+type editorRule struct {
+	Path    string `mapstructure:"path"`
+	Command string `mapstructure:"command"`
+}
+
+// editCmd represents the edit command
+var editCmd = &cobra.Command{
+	Use:   "edit <project>",
+	Short: "Open a project in an editor",
+	Long: `Open a project in an editor. The project is matched by path, then by
+exact, leading or partial name, then by the leading letters of the words
+of its name ("pdp" for platform-data-pipeline).
+
+The editor is $VISUAL, else $EDITOR, else vi. It can be chosen per path
+prefix in the config file, the longest matching prefix winning:
+
+  edit:
+    editors:
+      - path: ~/work
+        command: idea
+      - path: ~/projects
+        command: code --new-window
+    gui: [emacsclient]
+
+Terminal editors run attached to the terminal. GUI editors (code, idea,
+subl, ... and anything listed under edit.gui) are started in the
+background so that gum returns immediately. Without a terminal to run
+in, such as under cron or --non-interactive, a terminal editor is not
+started and gum edit fails instead.`,
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+
+	RunE: func(cmd *cobra.Command, args []string) error {
+		printOnly, _ := cmd.Flags().GetBool("print")
+		return doEdit(cmd.Context(), args[0], printOnly)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(editCmd)
+
+	editCmd.Flags().Bool("print", false, "Print the editor command instead of running it")
+}
+
+func doEdit(ctx context.Context, query string, printOnly bool) error {
+	projects, err := findProjects(ctx, runner)
+	if err != nil {
+		return err
+	}
+
+	project, err := resolveProject(query, projects)
+	if err != nil {
+		return err
+	}
+
+	argv, err := editorFor(project)
+	if err != nil {
+		return err
+	}
+	argv = append(argv, project)
+
+	if printOnly {
+		quoted := make([]string, len(argv))
+		for i, arg := range argv {
+			quoted[i] = shellQuote(arg)
+		}
+		fmt.Println(strings.Join(quoted, " "))
+		return nil
+	}
+
+	editor := exec.Command(argv[0], argv[1:]...)
+	editor.Dir = project
+
+	if isGUIEditor(argv[0]) {
+		log.Debugf("starting %v in the background", argv)
+		if err := editor.Start(); err != nil {
+			return fmt.Errorf("error starting editor %v: %w", argv[0], err)
+		}
+		return editor.Process.Release()
+	}
+
+	if !isInteractive() {
+		return fmt.Errorf("not starting %v without a terminal; use --print to get the command", argv[0])
+	}
+
+	editor.Stdin = os.Stdin
+	editor.Stdout = os.Stdout
+	editor.Stderr = os.Stderr
+	if err := editor.Run(); err != nil {
+		return fmt.Errorf("error running editor %v: %w", argv[0], err)
+	}
+	return nil
+}
+
+// editorFor returns the command line of the editor to open project with.
+func editorFor(project string) ([]string, error) {
+	var rules []editorRule
+	if err := viper.UnmarshalKey("edit.editors", &rules); err != nil {
+		return nil, fmt.Errorf("invalid edit.editors in config: %w", err)
+	}
+
+	command, longest := "", -1
+	for _, rule := range rules {
+		prefix := expandPath(rule.Path)
+		if pathWithin(project, prefix) && len(prefix) > longest {
+			command, longest = rule.Command, len(prefix)
+		}
+	}
+
+	for _, c := range []string{command, os.Getenv("VISUAL"), os.Getenv("EDITOR"), "vi"} {
+		if argv := strings.Fields(c); len(argv) > 0 {
+			return argv, nil
+		}
+	}
+	return nil, fmt.Errorf("no editor configured")
+}
+
+// isGUIEditor reports whether the editor program detaches from the terminal.
+func isGUIEditor(program string) bool {
+	name := filepath.Base(program)
+	for _, gui := range append(guiEditors, viper.GetStringSlice("edit.gui")...) {
+		if name == gui {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,186 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// fakeEditor writes an editor script named name to a new directory and
+// returns its path and the file it records its arguments and working
+// directory in, one per line. The script only records them once the file
+// returned third exists, so that tests can tell whether gum waited for it.
+func fakeEditor(t *testing.T, name string) (script, record, release string) {
+	t.Helper()
+	dir := t.TempDir()
+	script = filepath.Join(dir, name)
+	record = filepath.Join(dir, "argv")
+	release = filepath.Join(dir, "release")
+	writeFile(t, script, `#!/bin/sh
+while [ ! -e '`+release+`' ]; do sleep 0.01; done
+{ pwd; printf '%s\n' "$@"; } > '`+record+`.tmp' && mv '`+record+`.tmp' '`+record+`'
+`)
+	if err := os.Chmod(script, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return script, record, release
+}
+
+// editFixture makes a home with the projects ~/work/app and ~/oss/lib,
+// and returns their paths.
+func editFixture(t *testing.T) (app, lib string) {
+	t.Helper()
+	home := withHome(t)
+	withoutLocate(t)
+	withProjects(t, "~/work", "~/oss")
+	app, lib = filepath.Join(home, "work", "app"), filepath.Join(home, "oss", "lib")
+	for _, repo := range []string{app, lib} {
+		if err := os.MkdirAll(filepath.Join(repo, ".git"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv("VISUAL", "")
+	t.Setenv("EDITOR", "")
+	t.Cleanup(func() {
+		viper.Set("edit.editors", nil)
+		viper.Set("edit.gui", nil)
+	})
+	return app, lib
+}
+
+func TestEditPrint(t *testing.T) {
+	app, lib := editFixture(t)
+	t.Setenv("EDITOR", "my-editor --wait")
+	viper.Set("edit.editors", []map[string]any{{"path": "~/work", "command": "code --new-window"}})
+
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"app", "code --new-window " + app},
+		{"lib", "my-editor --wait " + lib},
+	}
+	for _, tt := range tests {
+		out := captureStdout(t, func() {
+			if err := doEdit(context.Background(), tt.query, true); err != nil {
+				t.Errorf("doEdit(%q) failed: %v", tt.query, err)
+			}
+		})
+		if out != tt.want+"\n" {
+			t.Errorf("doEdit(%q) printed %q, want %q", tt.query, out, tt.want)
+		}
+	}
+
+	home := filepath.Dir(filepath.Dir(app))
+	if err := os.MkdirAll(filepath.Join(home, "oss", "it's here", ".git"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	out := captureStdout(t, func() { doEdit(context.Background(), "here", true) })
+	if want := `my-editor --wait '` + home + `/oss/it'\''s here'` + "\n"; out != want {
+		t.Errorf("doEdit printed %q, want the path quoted for the shell, %q", out, want)
+	}
+}
+
+func TestEditStartsGUIEditorsDetached(t *testing.T) {
+	app, _ := editFixture(t)
+	script, record, release := fakeEditor(t, "fake-gui")
+	viper.Set("edit.gui", []string{"fake-gui"})
+	viper.Set("edit.editors", []map[string]any{{"path": "~/work", "command": script + " --reuse"}})
+	// GUI editors need no terminal.
+	saved := NonInteractive
+	t.Cleanup(func() { NonInteractive = saved })
+	NonInteractive = true
+
+	if err := doEdit(context.Background(), "app", false); err != nil {
+		t.Fatalf("doEdit failed: %v", err)
+	}
+	// The editor is still waiting for release, so gum did not wait for it.
+	if _, err := os.Stat(record); err == nil {
+		t.Fatalf("the editor finished before gum returned")
+	}
+	writeFile(t, release, "")
+
+	var data []byte
+	for deadline := time.Now().Add(10 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		if d, err := os.ReadFile(record); err == nil {
+			data = d
+			break
+		}
+	}
+	if got, want := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n"), []string{app, "--reuse", app}; !reflect.DeepEqual(got, want) {
+		t.Errorf("the editor ran in and with %q, want %q", got, want)
+	}
+}
+
+func TestEditRefusesTerminalEditorsWithoutTerminal(t *testing.T) {
+	editFixture(t)
+	script, record, release := fakeEditor(t, "fake-vi")
+	writeFile(t, release, "")
+	t.Setenv("EDITOR", script)
+	saved := NonInteractive
+	t.Cleanup(func() { NonInteractive = saved })
+	NonInteractive = true
+
+	err := doEdit(context.Background(), "lib", false)
+	if err == nil || !strings.Contains(err.Error(), "--print") {
+		t.Errorf("doEdit = %v, want an error suggesting --print", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if _, err := os.Stat(record); err == nil {
+		t.Errorf("the terminal editor was run without a terminal")
+	}
+}
+
+func TestEditorFor(t *testing.T) {
+	app, lib := editFixture(t)
+	viper.Set("edit.editors", []map[string]any{
+		{"path": "~", "command": "vim"},
+		{"path": "~/work", "command": "idea"},
+		{"path": "~/work/app", "command": "code --new-window"},
+	})
+
+	tests := []struct {
+		project, visual, editor string
+		want                    []string
+	}{
+		{app, "", "", []string{"code", "--new-window"}},
+		{filepath.Join(filepath.Dir(app), "api"), "", "", []string{"idea"}},
+		{lib, "emacs", "nano", []string{"vim"}},
+		{"/srv/other", "emacs -nw", "nano", []string{"emacs", "-nw"}},
+		{"/srv/other", "", "nano", []string{"nano"}},
+		{"/srv/other", "", "", []string{"vi"}},
+	}
+	for _, tt := range tests {
+		t.Setenv("VISUAL", tt.visual)
+		t.Setenv("EDITOR", tt.editor)
+		got, err := editorFor(tt.project)
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("editorFor(%q) with VISUAL=%q EDITOR=%q = %q, %v; want %q", tt.project, tt.visual, tt.editor, got, err, tt.want)
+		}
+	}
+}
+
+func TestIsGUIEditor(t *testing.T) {
+	viper.Set("edit.gui", []string{"emacsclient"})
+	t.Cleanup(func() { viper.Set("edit.gui", nil) })
+
+	for program, want := range map[string]bool{
+		"code":                  true,
+		"/usr/local/bin/idea":   true,
+		"emacsclient":           true,
+		"vi":                    false,
+		"nvim":                  false,
+		"/opt/code/bin/nano":    false,
+		"code-insiders-wrapper": false,
+	} {
+		if got := isGUIEditor(program); got != want {
+			t.Errorf("isGUIEditor(%q) = %v, want %v", program, got, want)
+		}
+	}
+}
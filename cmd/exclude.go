@@ -0,0 +1,60 @@
+/*
+Copyright © 2023 shalomb <s.bhooshi@gmail.com>
+*/
+package cmd
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/adrg/xdg"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// selfPaths returns the directories gum itself writes to. They are never
+// tracked as projects or frequent dirs, whatever the configuration says,
+// so that gum does not end up churning over its own state.
+func selfPaths() []string {
+	paths := []string{
+		filepath.Join(viper.GetString("CacheDir"), "gum"),
+		filepath.Join(xdg.ConfigHome, "gum"),
+	}
+
+	// Also match the resolved location so that symlinked cache or config
+	// dirs are caught when walking with find -L.
+	for _, p := range paths {
+		if r, err := filepath.EvalSymlinks(p); err == nil && r != p {
+			paths = append(paths, r)
+		}
+	}
+	return paths
+}
+
+// isSelfPath reports whether path is one of gum's own directories or lies
+// beneath one of them.
+func isSelfPath(path string) bool {
+	for _, p := range selfPaths() {
+		if pathWithin(path, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// warnSelfPaths logs a warning if a configured project directory is or
+// contains one of gum's own directories.
+func warnSelfPaths(dir string) {
+	for _, p := range selfPaths() {
+		if pathWithin(p, dir) {
+			log.Warnf("project directory %v contains %v, which gum uses for its own state and will not scan", dir, p)
+		}
+	}
+}
+
+// pathWithin reports whether path equals root or is nested under it.
+func pathWithin(path, root string) bool {
+	path = filepath.Clean(path)
+	root = filepath.Clean(root)
+	return path == root || strings.HasPrefix(path, strings.TrimSuffix(root, string(filepath.Separator))+string(filepath.Separator))
+}
@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/shalomb/gum/internal/execx"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// captureLog returns what gum logs during the rest of the test.
+func captureLog(t *testing.T) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	saved := log.StandardLogger().Out
+	log.SetOutput(&buf)
+	t.Cleanup(func() { log.SetOutput(saved) })
+	return &buf
+}
+
+func TestSelfPathsAreNotTracked(t *testing.T) {
+	home := withHome(t)
+	withoutLocate(t)
+	cache := filepath.Join(home, "state")
+	viper.Set("CacheDir", cache)
+	t.Cleanup(func() { viper.Set("CacheDir", nil) })
+	withProjects(t, "~/state", "~/.config")
+
+	// gum's own directories hold repositories, as a cache of clones or a
+	// config kept in git would.
+	own := []string{
+		filepath.Join(cache, "gum"),
+		filepath.Join(cache, "gum", "clones", "repo"),
+		filepath.Join(home, ".config", "gum"),
+	}
+	other := []string{
+		filepath.Join(cache, "other"),
+		filepath.Join(home, ".config", "nvim"),
+	}
+	for _, repo := range append(own, other...) {
+		if err := os.MkdirAll(filepath.Join(repo, ".git"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	logged := captureLog(t)
+
+	projects, err := findProjects(context.Background(), execx.Exec{})
+	if err != nil {
+		t.Fatalf("findProjects failed: %v", err)
+	}
+	if want := []string{other[1], other[0]}; !reflect.DeepEqual(projects, want) {
+		t.Errorf("findProjects = %q, want %q", projects, want)
+	}
+	for _, dir := range []string{own[0], own[2]} {
+		if !strings.Contains(logged.String(), "contains "+dir+", which gum uses for its own state") {
+			t.Errorf("no warning that a project directory contains %v in:\n%s", dir, logged)
+		}
+	}
+
+	workIn(t, own[1])
+	workIn(t, other[0])
+	sightings := sampleDirs(context.Background())
+	if sightings[own[1]] > 0 {
+		t.Errorf("sampleDirs counted %v, which is gum's own", own[1])
+	}
+	if sightings[other[0]] == 0 {
+		t.Errorf("sampleDirs missed %v", other[0])
+	}
+}
+
+func TestIsSelfPath(t *testing.T) {
+	home := withHome(t)
+	viper.Set("CacheDir", filepath.Join(home, ".cache"))
+	t.Cleanup(func() { viper.Set("CacheDir", nil) })
+
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{filepath.Join(home, ".cache", "gum"), true},
+		{filepath.Join(home, ".cache", "gum", "sub"), true},
+		{filepath.Join(home, ".config", "gum"), true},
+		{filepath.Join(home, ".cache"), false},
+		{filepath.Join(home, ".cache", "gum-other"), false},
+		{filepath.Join(home, ".config", "gumtree"), false},
+	}
+	for _, tt := range tests {
+		if got := isSelfPath(tt.path); got != tt.want {
+			t.Errorf("isSelfPath(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+
+	// A symlinked cache is recognised by its real path too.
+	real := filepath.Join(home, "elsewhere")
+	if err := os.MkdirAll(filepath.Join(real, "gum"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(real, filepath.Join(home, "linked")); err != nil {
+		t.Fatal(err)
+	}
+	viper.Set("CacheDir", filepath.Join(home, "linked"))
+	if !isSelfPath(filepath.Join(real, "gum", "x")) {
+		t.Errorf("isSelfPath missed the real path of a symlinked cache directory")
+	}
+}
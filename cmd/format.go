@@ -0,0 +1,68 @@
+/*
+Copyright © 2023 shalomb <s.bhooshi@gmail.com>
+*/
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Line formats shared by the commands that print listings and by gum
+// resolve, which turns any of those lines back into a path. Keep the two
+// sides in step when changing either.
+
+// fieldSep separates the fields of a listing line.
+const fieldSep = "\t"
+
+// formatDirLine renders a dirs entry as "frequency<TAB>path", followed by
+// "<TAB>annotation" if there is one.
+func formatDirLine(e dirEntry, annotation string) string {
+	line := fmt.Sprintf("%v%s%v", e.Frequency, fieldSep, e.Path)
+	if annotation != "" {
+		line += fieldSep + annotation
+	}
+	return line
+}
+
+// lineCandidates returns the paths a listing line may stand for, most
+// likely first: the path of a JSON object such as jq -c '.[]' makes of
+// --format json, the path field of a tab-separated line, the line as a
+// plain path, and the line without a trailing " (annotation)". Grouped
+// listings indent their paths, so leading spaces are dropped.
+func lineCandidates(line string) []string {
+	line = strings.TrimRight(line, "\r\n")
+
+	var candidates []string
+	if strings.HasPrefix(line, "{") {
+		var object struct {
+			Path string `json:"path"`
+		}
+		if json.Unmarshal([]byte(line), &object) == nil && object.Path != "" {
+			candidates = append(candidates, object.Path)
+		}
+	}
+	line = strings.TrimLeft(line, " ")
+	if _, rest, ok := strings.Cut(line, fieldSep); ok {
+		path, _, _ := strings.Cut(rest, fieldSep)
+		candidates = append(candidates, path)
+	}
+	candidates = append(candidates, line)
+	if i := strings.LastIndex(line, " ("); i > 0 && strings.HasSuffix(line, ")") {
+		candidates = append(candidates, line[:i])
+	}
+	return candidates
+}
+
+// writeJSON writes v to w as JSON, indented if pretty. HTML characters are
+// left alone since paths are not embedded in HTML.
+func writeJSON(w io.Writer, v any, pretty bool) error {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	if pretty {
+		enc.SetIndent("", "  ")
+	}
+	return enc.Encode(v)
+}
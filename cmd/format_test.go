@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/shalomb/gum/internal/manifest"
+)
+
+// The JSON fields of dirs and projects are part of gum's output contract:
+// these tests fail when one is renamed or removed.
+
+func TestDirJSONFields(t *testing.T) {
+	data, err := json.Marshal(dirJSON{
+		Path: "/p/gum", Frequency: 3, LastSeen: "2023-05-01T09:00:00Z", Subdir: "/p/gum/cmd", Project: "/p/gum",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"path":"/p/gum","frequency":3,"last_seen":"2023-05-01T09:00:00Z","subdir":"/p/gum/cmd","project":"/p/gum"}`
+	if string(data) != want {
+		t.Errorf("dirJSON is\n%s\nwant\n%s", data, want)
+	}
+}
+
+func TestProjectJSONFields(t *testing.T) {
+	data, err := json.Marshal(projectJSON{
+		Path:        "/p/gum",
+		Remote:      "git@github.com:shalomb/gum.git",
+		Branch:      "main",
+		Identifiers: []manifest.Identifier{{Kind: "go", Value: "github.com/shalomb/gum"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"path":"/p/gum","remote":"git@github.com:shalomb/gum.git","branch":"main","worktree":false,"bare":false,` +
+		`"identifiers":[{"kind":"go","value":"github.com/shalomb/gum"}]}`
+	if string(data) != want {
+		t.Errorf("projectJSON is\n%s\nwant\n%s", data, want)
+	}
+}
+
+func TestRenderDirsJSON(t *testing.T) {
+	seen := time.Date(2023, 5, 1, 11, 0, 0, 0, time.FixedZone("CEST", 2*60*60))
+	dirs := map[string]dirEntry{
+		"/tmp":   {Path: "/tmp", Frequency: 1, LastSeen: seen},
+		"/p/gum": {Path: "/p/gum", Frequency: 4, LastSeen: seen, Subdir: "/p/gum/cmd", Project: "/p/gum"},
+	}
+	out := captureStdout(t, func() {
+		if err := renderDirs(dirs, dirsOptions{Format: "json"}); err != nil {
+			t.Errorf("renderDirs failed: %v", err)
+		}
+	})
+
+	var got []map[string]any
+	if err := json.Unmarshal([]byte(out), &got); err != nil {
+		t.Fatalf("renderDirs printed invalid JSON %q: %v", out, err)
+	}
+	want := []map[string]any{
+		{"path": "/p/gum", "frequency": 4.0, "last_seen": "2023-05-01T09:00:00Z", "subdir": "/p/gum/cmd", "project": "/p/gum"},
+		{"path": "/tmp", "frequency": 1.0, "last_seen": "2023-05-01T09:00:00Z", "subdir": "", "project": ""},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("renderDirs printed %v, want %v", got, want)
+	}
+
+	out = captureStdout(t, func() { renderDirs(nil, dirsOptions{Format: "json"}) })
+	if out != "[]\n" {
+		t.Errorf("renderDirs printed %q for no entries, want an empty array", out)
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	v := map[string]string{"path": "/p/a&b<c>"}
+	var compact, pretty bytes.Buffer
+	if err := writeJSON(&compact, v, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeJSON(&pretty, v, true); err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"path":"/p/a&b<c>"}` + "\n"; compact.String() != want {
+		t.Errorf("writeJSON = %q, want %q", compact.String(), want)
+	}
+	if want := "{\n  \"path\": \"/p/a&b<c>\"\n}\n"; pretty.String() != want {
+		t.Errorf("writeJSON pretty = %q, want %q", pretty.String(), want)
+	}
+}
+
+func TestLineCandidates(t *testing.T) {
+	tests := []struct {
+		line string
+		want []string
+	}{
+		{"/p/gum\n", []string{"/p/gum"}},
+		{"/p/gum\r\n", []string{"/p/gum"}},
+		{"12\t/p/gum", []string{"/p/gum", "12\t/p/gum"}},
+		{"12\t/p/gum/cmd\t[in:gum]", []string{"/p/gum/cmd", "12\t/p/gum/cmd\t[in:gum]"}},
+		{"/p/gum (2 days ago)", []string{"/p/gum (2 days ago)", "/p/gum"}},
+		{"/p/my project (old)/src", []string{"/p/my project (old)/src"}},
+		{"  /p/gum (last used 2023-05-01 09:00, keep)", []string{"/p/gum (last used 2023-05-01 09:00, keep)", "/p/gum"}},
+		{`{"path":"/p/gum\tx","frequency":3}`, []string{"/p/gum\tx", `{"path":"/p/gum\tx","frequency":3}`}},
+		{"", []string{""}},
+	}
+	for _, tt := range tests {
+		if got := lineCandidates(tt.line); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("lineCandidates(%q) = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+
+	// Every line gum dirs prints stands first for its path.
+	e := dirEntry{Path: "/p/my project", Frequency: 7}
+	for _, annotation := range []string{"", "[repo]"} {
+		if got := lineCandidates(formatDirLine(e, annotation))[0]; got != e.Path {
+			t.Errorf("the dirs line %q stands first for %q, want %q", formatDirLine(e, annotation), got, e.Path)
+		}
+	}
+}
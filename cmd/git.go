@@ -0,0 +1,221 @@
+/*
+Copyright © 2023 shalomb <s.bhooshi@gmail.com>
+*/
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/shalomb/gum/internal/execx"
+)
+
+// gitDirs returns the git directory of the repository at repo, holding its
+// HEAD and index, and the common directory holding its config and objects.
+// The two differ for linked worktrees, whose .git is a file pointing into
+// the main repository. A bare repository is its own git directory.
+func gitDirs(repo string) (dir, common string, err error) {
+	dotgit := filepath.Join(repo, ".git")
+	info, err := os.Stat(dotgit)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		return repo, repo, nil
+	case err != nil:
+		return "", "", err
+	case info.IsDir():
+		return dotgit, dotgit, nil
+	}
+
+	data, err := os.ReadFile(dotgit)
+	if err != nil {
+		return "", "", err
+	}
+	dir, ok := strings.CutPrefix(strings.TrimSpace(string(data)), "gitdir: ")
+	if !ok {
+		return "", "", fmt.Errorf("%v does not point to a git directory", dotgit)
+	}
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(repo, dir)
+	}
+
+	common = dir
+	if data, err := os.ReadFile(filepath.Join(dir, "commondir")); err == nil {
+		common = strings.TrimSpace(string(data))
+		if !filepath.IsAbs(common) {
+			common = filepath.Join(dir, common)
+		}
+	}
+	return filepath.Clean(dir), filepath.Clean(common), nil
+}
+
+// isGitWorktree reports whether repo is a linked worktree of another
+// repository.
+func isGitWorktree(repo string) bool {
+	dir, common, err := gitDirs(repo)
+	return err == nil && dir != common
+}
+
+// isBareGitRepo reports whether dir is a bare repository: it has no
+// working tree, but a HEAD, objects and refs of its own.
+func isBareGitRepo(dir string) bool {
+	if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+		return false
+	}
+	for _, name := range []string{"objects", "refs"} {
+		if info, err := os.Stat(filepath.Join(dir, name)); err != nil || !info.IsDir() {
+			return false
+		}
+	}
+	info, err := os.Stat(filepath.Join(dir, "HEAD"))
+	return err == nil && info.Mode().IsRegular()
+}
+
+// gitRemoteURL returns the URL of the origin remote of the repository at
+// repo, or of its first remote if there is no origin. It reads the config
+// file directly rather than running git, which matters when listing
+// hundreds of repositories. An empty URL means the repository has no remotes.
+func gitRemoteURL(repo string) (string, error) {
+	_, common, err := gitDirs(repo)
+	if err != nil {
+		return "", err
+	}
+	f, err := os.Open(filepath.Join(common, "config"))
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	var section, first string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "[") {
+			section = line
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok || strings.TrimSpace(key) != "url" || !strings.HasPrefix(section, `[remote "`) {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		if section == `[remote "origin"]` {
+			return value, nil
+		}
+		if first == "" {
+			first = value
+		}
+	}
+	return first, scanner.Err()
+}
+
+// repoIdentity normalises a remote URL to "host/owner/repo" so that every
+// checkout of a repository maps to the same identity however it was
+// cloned: scp-like ssh, ssh://, https:// or git://, with or without .git,
+// in any letter case. Local paths and file:// URLs map to the cleaned
+// path. An empty URL has no identity.
+func repoIdentity(remote string) string {
+	if remote == "" {
+		return ""
+	}
+
+	var host, path string
+	if u, err := url.Parse(remote); err == nil && u.Scheme != "" && u.Host != "" {
+		host, path = u.Hostname(), u.Path
+	} else if u != nil && u.Scheme == "file" {
+		return filepath.Clean(u.Path)
+	} else if i := strings.Index(remote, ":"); i > 0 && !strings.Contains(remote[:i], "/") {
+		// scp-like syntax: [user@]host:owner/repo
+		host, path = remote[:i], remote[i+1:]
+		if at := strings.LastIndex(host, "@"); at >= 0 {
+			host = host[at+1:]
+		}
+	} else {
+		return filepath.Clean(remote)
+	}
+
+	path = strings.Trim(path, "/")
+	path = strings.TrimSuffix(path, ".git")
+	return strings.ToLower(host + "/" + path)
+}
+
+// repoOwner returns the owner part of a "host/owner/repo" identity, or an
+// empty string for identities without one, such as local paths.
+func repoOwner(identity string) string {
+	parts := strings.Split(identity, "/")
+	if strings.HasPrefix(identity, "/") || len(parts) < 3 {
+		return ""
+	}
+	return parts[1]
+}
+
+// gitBranch returns the branch checked out in the repository at repo, read
+// from its HEAD, or an empty string if HEAD is detached.
+func gitBranch(repo string) (string, error) {
+	dir, _, err := gitDirs(repo)
+	if err != nil {
+		return "", err
+	}
+	head, err := os.ReadFile(filepath.Join(dir, "HEAD"))
+	if err != nil {
+		return "", err
+	}
+	ref, ok := strings.CutPrefix(strings.TrimSpace(string(head)), "ref: ")
+	if !ok {
+		return "", nil
+	}
+	return strings.TrimPrefix(ref, "refs/heads/"), nil
+}
+
+// gitLastUsed returns when the repository at repo was last used, as the
+// later of the modification times of its HEAD, which changes on checkout
+// and commit, and its index, which most other commands rewrite.
+func gitLastUsed(repo string) (time.Time, error) {
+	var last time.Time
+	dir, _, err := gitDirs(repo)
+	if err != nil {
+		return last, err
+	}
+	for _, name := range []string{"HEAD", "index"} {
+		info, err := os.Stat(filepath.Join(dir, name))
+		if errors.Is(err, fs.ErrNotExist) && name == "index" {
+			continue
+		}
+		if err != nil {
+			return last, err
+		}
+		if info.ModTime().After(last) {
+			last = info.ModTime()
+		}
+	}
+	return last, nil
+}
+
+// errGitNotFound is returned by the commands that need git when it is not
+// installed.
+var errGitNotFound = errors.New("git not found: install git or add it to PATH")
+
+// gitDirty reports whether the repository at repo has uncommitted changes,
+// including untracked files.
+func gitDirty(ctx context.Context, r execx.Runner, repo string) (bool, error) {
+	stdout, stderr, err := r.Run(ctx, repo, "git", "status", "--porcelain")
+	switch {
+	case errors.Is(err, exec.ErrNotFound):
+		return false, errGitNotFound
+	case err != nil && len(bytes.TrimSpace(stderr)) > 0:
+		return false, fmt.Errorf("%w: %s", err, bytes.TrimSpace(stderr))
+	case err != nil:
+		return false, err
+	}
+	return len(bytes.TrimSpace(stdout)) > 0, nil
+}
@@ -0,0 +1,35 @@
+/*
+Copyright © 2023 shalomb <s.bhooshi@gmail.com>
+*/
+package cmd
+
+import (
+	"os"
+	"strconv"
+
+	"golang.org/x/term"
+)
+
+// isInteractive reports whether gum may wait for the user, such as by
+// prompting or running a terminal program in the foreground. It is false
+// when stdin is not a terminal, with --non-interactive, or when
+// $GUM_NON_INTERACTIVE is set to anything but a false value, so that cron
+// jobs and CI never hang on input.
+//
+// Every feature that would wait for the user must check it and take its
+// documented non-interactive path instead:
+//
+//   - gum edit with a terminal editor fails, suggesting --print
+//   - gum clone runs git with prompts for credentials and host keys turned
+//     off, so that it fails instead
+func isInteractive() bool {
+	if NonInteractive {
+		return false
+	}
+	if env := os.Getenv("GUM_NON_INTERACTIVE"); env != "" {
+		if set, err := strconv.ParseBool(env); err != nil || set {
+			return false
+		}
+	}
+	return term.IsTerminal(int(os.Stdin.Fd()))
+}
@@ -0,0 +1,56 @@
+/*
+Copyright © 2023 shalomb <s.bhooshi@gmail.com>
+*/
+package cmd
+
+import (
+	"os"
+	"strconv"
+
+	"golang.org/x/term"
+)
+
+// outputWidth returns the number of columns human-readable listings should
+// fit in, or 0 if they must not be truncated. --width always wins; without
+// it, output is only fitted to the terminal when stdout is one, using
+// $COLUMNS if set and the terminal size otherwise. Output that is piped or
+// redirected is never truncated.
+func outputWidth() int {
+	if Width > 0 {
+		return Width
+	}
+
+	fd := int(os.Stdout.Fd())
+	if !term.IsTerminal(fd) {
+		return 0
+	}
+	if columns, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && columns > 0 {
+		return columns
+	}
+	if columns, _, err := term.GetSize(fd); err == nil && columns > 0 {
+		return columns
+	}
+	return 0
+}
+
+// truncateMiddle shortens s to at most width characters by replacing its
+// middle with an ellipsis, which keeps both the root and the leaf of a path
+// readable. Widths of 0 or less leave s alone.
+func truncateMiddle(s string, width int) string {
+	r := []rune(s)
+	if width <= 0 || len(r) <= width {
+		return s
+	}
+	if width == 1 {
+		return "…"
+	}
+	head := (width - 1) / 2
+	tail := width - 1 - head
+	return string(r[:head]) + "…" + string(r[len(r)-tail:])
+}
+
+// tabStop returns the column the next field starts at after printing s
+// followed by a tab.
+func tabStop(s string) int {
+	return (len([]rune(s))/8 + 1) * 8
+}
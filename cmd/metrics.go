@@ -0,0 +1,114 @@
+/*
+Copyright © 2023 shalomb <s.bhooshi@gmail.com>
+*/
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// metricsCmd represents the metrics command
+var metricsCmd = &cobra.Command{
+	Use:   "metrics",
+	Short: "Print gum metrics in the Prometheus text format",
+	Long: `Print gum metrics in the Prometheus text exposition format, or write them
+to a file for the node_exporter textfile collector, e.g. from cron:
+
+  */15 * * * * gum metrics --textfile /var/lib/node_exporter/textfile/gum.prom
+
+The file is replaced atomically so the collector never reads a partial
+file. Metric names, types and help strings are stable.`,
+	Args:         cobra.NoArgs,
+	SilenceUsage: true,
+
+	RunE: func(cmd *cobra.Command, args []string) error {
+		textfile, _ := cmd.Flags().GetString("textfile")
+		stdout, _ := cmd.Flags().GetBool("stdout")
+
+		var buf bytes.Buffer
+		if err := writeMetrics(cmd.Context(), &buf); err != nil {
+			return err
+		}
+
+		if textfile != "" {
+			if err := writeFileAtomic(textfile, buf.Bytes(), 0o644); err != nil {
+				return err
+			}
+		}
+		if textfile == "" || stdout {
+			_, err := os.Stdout.Write(buf.Bytes())
+			return err
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(metricsCmd)
+
+	metricsCmd.Flags().String("textfile", "", "Write the metrics to this file, replacing it atomically")
+	metricsCmd.Flags().Bool("stdout", false, "Also print the metrics when writing --textfile")
+}
+
+// writeMetrics collects the metrics and writes them to w.
+func writeMetrics(ctx context.Context, w io.Writer) error {
+	start := time.Now()
+	projects, err := findProjects(ctx, runner)
+	if err != nil {
+		return err
+	}
+	discovery := time.Since(start)
+
+	info := getBuildInfo()
+
+	writeGauge(w, "gum_build_info", "Build information about the gum binary, always 1.",
+		map[string]string{"version": info.Version, "commit": info.Commit, "goversion": info.GoVersion}, 1)
+	writeGauge(w, "gum_projects", "Number of git repositories found under the configured project directories.",
+		nil, float64(len(projects)))
+	writeGauge(w, "gum_project_discovery_duration_seconds", "Time taken to discover the projects.",
+		nil, discovery.Seconds())
+	writeGauge(w, "gum_active_dirs", "Number of distinct working directories of running processes.",
+		nil, float64(len(sampleDirs(ctx))))
+	writeGauge(w, "gum_last_run_timestamp_seconds", "Unix time at which these metrics were collected.",
+		nil, float64(time.Now().Unix()))
+	return nil
+}
+
+// labelEscaper escapes label values as the Prometheus text format requires.
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// writeGauge writes a single gauge sample with its HELP and TYPE lines.
+func writeGauge(w io.Writer, name, help string, labels map[string]string, value float64) {
+	fmt.Fprintf(w, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(w, "# TYPE %s gauge\n", name)
+
+	var pairs []string
+	for _, k := range sortedKeys(labels) {
+		pairs = append(pairs, fmt.Sprintf(`%s="%s"`, k, labelEscaper.Replace(labels[k])))
+	}
+	if len(pairs) > 0 {
+		fmt.Fprintf(w, "%s{%s} %s\n", name, strings.Join(pairs, ","), strconv.FormatFloat(value, 'f', -1, 64))
+	} else {
+		fmt.Fprintf(w, "%s %s\n", name, strconv.FormatFloat(value, 'f', -1, 64))
+	}
+}
+
+// sortedKeys returns the keys of m in order, so output is deterministic.
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/expfmt"
+)
+
+// parseMetrics parses out as the Prometheus text format.
+func parseMetrics(t *testing.T, out string) map[string]*dto.MetricFamily {
+	t.Helper()
+	var parser expfmt.TextParser
+	families, err := parser.TextToMetricFamilies(strings.NewReader(out))
+	if err != nil {
+		t.Fatalf("invalid metrics: %v\n%s", err, out)
+	}
+	return families
+}
+
+func TestMetrics(t *testing.T) {
+	home := withHome(t)
+	withoutLocate(t)
+	withProjects(t, "~/src")
+	for _, repo := range []string{"a", "b", "org/c"} {
+		if err := os.MkdirAll(filepath.Join(home, "src", repo, ".git"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	families := parseMetrics(t, runGum(t, "metrics"))
+
+	want := map[string]string{
+		"gum_build_info":                         "Build information about the gum binary, always 1.",
+		"gum_projects":                           "Number of git repositories found under the configured project directories.",
+		"gum_project_discovery_duration_seconds": "Time taken to discover the projects.",
+		"gum_active_dirs":                        "Number of distinct working directories of running processes.",
+		"gum_last_run_timestamp_seconds":         "Unix time at which these metrics were collected.",
+	}
+	if len(families) != len(want) {
+		t.Errorf("got %v metrics, want %v", len(families), len(want))
+	}
+	for name, help := range want {
+		family, ok := families[name]
+		if !ok {
+			t.Errorf("no metric %v", name)
+			continue
+		}
+		if family.GetType() != dto.MetricType_GAUGE {
+			t.Errorf("%v is a %v, want a gauge", name, family.GetType())
+		}
+		if family.GetHelp() != help {
+			t.Errorf("%v has help %q, want %q", name, family.GetHelp(), help)
+		}
+		if len(family.GetMetric()) != 1 {
+			t.Errorf("%v has %v samples, want 1", name, len(family.GetMetric()))
+		}
+	}
+
+	if got := families["gum_projects"].GetMetric()[0].GetGauge().GetValue(); got != 3 {
+		t.Errorf("gum_projects = %v, want 3", got)
+	}
+	labels := map[string]bool{}
+	for _, l := range families["gum_build_info"].GetMetric()[0].GetLabel() {
+		labels[l.GetName()] = true
+	}
+	for _, name := range []string{"version", "commit", "goversion"} {
+		if !labels[name] {
+			t.Errorf("gum_build_info has no %v label", name)
+		}
+	}
+}
+
+func TestMetricsTextfile(t *testing.T) {
+	home := withHome(t)
+	withoutLocate(t)
+	withProjects(t, "~/src")
+	textfile := filepath.Join(home, "gum.prom")
+
+	if out := runGum(t, "metrics", "--textfile", textfile); out != "" {
+		t.Errorf("gum metrics --textfile printed %q, want nothing", out)
+	}
+	data, err := os.ReadFile(textfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := parseMetrics(t, string(data))["gum_projects"].GetMetric()[0].GetGauge().GetValue(); got != 0 {
+		t.Errorf("gum_projects = %v in the textfile, want 0", got)
+	}
+
+	out := runGum(t, "metrics", "--textfile", textfile, "--stdout")
+	if _, ok := parseMetrics(t, out)["gum_build_info"]; !ok {
+		t.Errorf("gum metrics --stdout printed no gum_build_info in\n%s", out)
+	}
+}
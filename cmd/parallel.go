@@ -0,0 +1,41 @@
+/*
+Copyright © 2023 shalomb <s.bhooshi@gmail.com>
+*/
+package cmd
+
+import (
+	"runtime"
+	"sync"
+)
+
+// jobs returns how many tasks gum runs at once: --jobs, or else the number
+// of CPUs.
+func jobs() int {
+	if Jobs > 0 {
+		return Jobs
+	}
+	return runtime.NumCPU()
+}
+
+// forEachParallel calls f with every index from 0 to n-1, running up to
+// jobs() calls at a time, and returns when all are done. Callers store
+// results by index so that their order does not depend on timing.
+func forEachParallel(n int, f func(i int)) {
+	indexes := make(chan int)
+
+	var wg sync.WaitGroup
+	for w := 0; w < min(jobs(), n); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				f(i)
+			}
+		}()
+	}
+	for i := 0; i < n; i++ {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+}
@@ -0,0 +1,149 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/shalomb/gum/internal/execx"
+	log "github.com/sirupsen/logrus"
+)
+
+func TestForEachParallel(t *testing.T) {
+	saved := Jobs
+	t.Cleanup(func() { Jobs = saved })
+
+	for _, jobs := range []int{1, 3, 64} {
+		Jobs = jobs
+		var running, most atomic.Int32
+		done := make([]int, 20)
+		forEachParallel(len(done), func(i int) {
+			n := running.Add(1)
+			for m := most.Load(); n > m && !most.CompareAndSwap(m, n); m = most.Load() {
+			}
+			time.Sleep(time.Millisecond)
+			done[i]++
+			running.Add(-1)
+		})
+
+		for i, n := range done {
+			if n != 1 {
+				t.Errorf("--jobs %d: f(%d) ran %d times, want once", jobs, i, n)
+			}
+		}
+		if m := int(most.Load()); m > jobs {
+			t.Errorf("--jobs %d: ran %d calls at once", jobs, m)
+		}
+	}
+}
+
+// makeRepos makes n repositories under dir, spread over a few owners, and
+// returns their paths.
+func makeRepos(tb testing.TB, dir string, n int) []string {
+	tb.Helper()
+	var repos []string
+	for i := 0; i < n; i++ {
+		repo := filepath.Join(dir, fmt.Sprintf("org%d", i%5), fmt.Sprintf("repo%d", i))
+		if err := os.MkdirAll(filepath.Join(repo, ".git"), 0o755); err != nil {
+			tb.Fatal(err)
+		}
+		repos = append(repos, repo)
+	}
+	return repos
+}
+
+// delayedRunner runs programs for real, each find only after the delay
+// given for the first directory it searches.
+type delayedRunner struct {
+	execx.Exec
+	delays map[string]time.Duration
+
+	mu    sync.Mutex
+	order []string
+}
+
+func (r *delayedRunner) Run(ctx context.Context, dir, name string, args ...string) ([]byte, []byte, error) {
+	root := args[1] // after -L
+	time.Sleep(r.delays[root])
+	stdout, stderr, err := execx.Exec{}.Run(ctx, dir, name, args...)
+
+	r.mu.Lock()
+	r.order = append(r.order, root)
+	r.mu.Unlock()
+	return stdout, stderr, err
+}
+
+func TestFindProjectsOrderIsStable(t *testing.T) {
+	home := withHome(t)
+	withoutLocate(t)
+	savedJobs := Jobs
+	t.Cleanup(func() { Jobs = savedJobs })
+
+	// The first directory's search finishes last, the last one's first.
+	names := []string{"a", "b", "c", "d"}
+	var dirs []any
+	delays := map[string]time.Duration{}
+	for i, name := range names {
+		makeRepos(t, filepath.Join(home, name), 10)
+		dirs = append(dirs, "~/"+name)
+		delays[filepath.Join(home, name)] = time.Duration(len(names)-i) * 20 * time.Millisecond
+	}
+	withProjects(t, dirs...)
+
+	var want []string
+	for _, jobs := range []int{1, 2, len(names)} {
+		Jobs = jobs
+		r := &delayedRunner{delays: delays}
+		got, err := findProjects(context.Background(), r)
+		if err != nil {
+			t.Fatalf("--jobs %d: findProjects failed: %v", jobs, err)
+		}
+		if len(got) != 10*len(names) {
+			t.Fatalf("--jobs %d: found %d projects, want %d", jobs, len(got), 10*len(names))
+		}
+		if want == nil {
+			want = got
+		} else if !reflect.DeepEqual(got, want) {
+			t.Errorf("--jobs %d listed\n%q\nwant\n%q", jobs, got, want)
+		}
+		if jobs == len(names) && r.order[0] != filepath.Join(home, names[len(names)-1]) {
+			t.Errorf("--jobs %d: searches finished in the order %q; want the last directory first", jobs, r.order)
+		}
+	}
+}
+
+func BenchmarkFindProjects(b *testing.B) {
+	home := withHome(b)
+	withoutLocate(b)
+	var dirs []any
+	for _, name := range []string{"src", "work", "oss", "scratch"} {
+		makeRepos(b, filepath.Join(home, name), 100)
+		dirs = append(dirs, "~/"+name)
+	}
+	withProjects(b, dirs...)
+	savedJobs, savedLevel := Jobs, log.GetLevel()
+	b.Cleanup(func() {
+		Jobs = savedJobs
+		log.SetLevel(savedLevel)
+	})
+	log.SetLevel(log.WarnLevel)
+
+	for _, jobs := range []int{1, runtime.NumCPU()} {
+		b.Run(fmt.Sprintf("jobs=%d", jobs), func(b *testing.B) {
+			Jobs = jobs
+			for i := 0; i < b.N; i++ {
+				projects, err := findProjects(context.Background(), execx.Exec{})
+				if err != nil || len(projects) != 400 {
+					b.Fatalf("findProjects found %d projects: %v", len(projects), err)
+				}
+			}
+		})
+	}
+}
@@ -5,23 +5,74 @@ Copyright © 2023 NAME HERE <EMAIL ADDRESS>
 package cmd
 
 import (
+	"bufio"
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"time"
 
+	"github.com/shalomb/gum/internal/execx"
+	"github.com/shalomb/gum/internal/manifest"
+	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 )
 
 // projectsCmd represents the projects command
 var projectsCmd = &cobra.Command{
 	Use:   "projects",
-	Short: "A brief description of your command",
-	Long: `A longer description that spans multiple lines and likely contains examples
-and usage of using your command. For example:
-
-Cobra is a CLI library for Go that empowers applications.
-This application is a tool to generate the needed files
-to quickly create a Cobra application.`,
-	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Println("projects called")
+	Short: "List the git repositories under the configured project directories",
+	Long: `List the git repositories found under the project directories configured
+in config.yaml, one absolute path per line, in natural order.
+
+  gum projects | fzf
+  gum projects -0 | xargs -0 -n1 git -C
+
+--format null is the same as -0.
+
+--org keeps the repositories whose remote belongs to that owner, however
+the remote is spelled, and --dirty those with uncommitted changes. Filters
+combine, and apply before sorting and --limit. --dirty needs git, and
+keeps the repositories git cannot read the status of, with a warning.
+
+--duplicates lists only the repositories checked out more than once, with
+when each checkout was last used and whether it has uncommitted changes,
+and marks the most recently used clean checkout as the one to keep.
+
+--sort recent lists the most recently used repositories first, going by
+when their HEAD or index last changed. --limit caps the listing, in any
+format, after sorting.
+
+With --group-by repo, checkouts of the same repository are listed together
+under the repository they were cloned from, as host/owner/repo, however
+their remote URLs are spelled. Repositories without a remote are listed
+last, each on its own.
+
+Linked worktrees and bare repositories are listed too. Set
+discovery.worktrees to false in the config to leave worktrees out.
+
+--format json prints an array of {"path", "remote", "branch", "worktree",
+"bare", "identifiers"} objects, the identifiers being the {"kind",
+"value"} names declared in go.mod, package.json, Cargo.toml or
+pyproject.toml. Every field is always present; remote and branch are
+empty strings when there is none.`,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var opts projectsOptions
+		opts.Print0, _ = cmd.Flags().GetBool("print0")
+		opts.GroupBy, _ = cmd.Flags().GetString("group-by")
+		opts.Format, _ = cmd.Flags().GetString("format")
+		opts.Pretty, _ = cmd.Flags().GetBool("pretty")
+		opts.Sort, _ = cmd.Flags().GetString("sort")
+		opts.Limit, _ = cmd.Flags().GetInt("limit")
+		opts.Org, _ = cmd.Flags().GetString("org")
+		opts.Dirty, _ = cmd.Flags().GetBool("dirty")
+		opts.Duplicates, _ = cmd.Flags().GetBool("duplicates")
+		return doProjects(cmd.Context(), opts)
 	},
 }
 
@@ -36,5 +87,351 @@ func init() {
 
 	// Cobra supports local flags which will only run when this command
 	// is called directly, e.g.:
-	// projectsCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+	projectsCmd.Flags().BoolP("print0", "0", false, "Print each path terminated by a NUL byte (for xargs -0, fzf --read0)")
+	projectsCmd.Flags().String("group-by", "", "Group the listing: repo (checkouts of the same repository)")
+	projectsCmd.Flags().StringP("format", "f", "default", "Output format (default, json, null)")
+	projectsCmd.Flags().Bool("pretty", false, "Indent JSON output")
+	projectsCmd.Flags().String("sort", "path", "Order of the listing: "+strings.Join(projectSortKeys, ", "))
+	projectsCmd.Flags().IntP("limit", "n", 0, "List at most this many projects (0 for all)")
+	projectsCmd.Flags().String("org", "", "Only list projects whose remote belongs to this owner")
+	projectsCmd.Flags().Bool("dirty", false, "Only list projects with uncommitted changes")
+	projectsCmd.Flags().Bool("duplicates", false, "List the repositories checked out more than once")
+}
+
+// projectSortKeys are the valid values of --sort.
+var projectSortKeys = []string{"path", "recent"}
+
+// projectsOptions controls how gum projects prints its listing.
+type projectsOptions struct {
+	// Print0 prints NUL-terminated paths instead of lines.
+	Print0 bool
+	// GroupBy is "repo" to group checkouts by their remote, or empty.
+	GroupBy string
+	// Format is "default", "json" or "null", which is the same as Print0.
+	Format string
+	// Pretty indents JSON output.
+	Pretty bool
+	// Sort is one of projectSortKeys.
+	Sort string
+	// Limit caps the number of projects listed, if positive.
+	Limit int
+	// Org keeps only the projects whose remote owner matches, if set.
+	Org string
+	// Dirty keeps only the projects with uncommitted changes.
+	Dirty bool
+	// Duplicates lists the repositories checked out more than once.
+	Duplicates bool
+}
+
+// projectJSON is the JSON representation of a project. Its fields are
+// part of gum's output contract: add to it, but do not rename or remove.
+type projectJSON struct {
+	Path        string                `json:"path"`
+	Remote      string                `json:"remote"`
+	Branch      string                `json:"branch"`
+	Worktree    bool                  `json:"worktree"`
+	Bare        bool                  `json:"bare"`
+	Identifiers []manifest.Identifier `json:"identifiers"`
+}
+
+func doProjects(ctx context.Context, opts projectsOptions) error {
+	switch opts.GroupBy {
+	case "", "repo":
+	default:
+		return fmt.Errorf("invalid --group-by %q: expected repo", opts.GroupBy)
+	}
+	if err := checkProjectsFormat(&opts); err != nil {
+		return err
+	}
+	if !slices.Contains(projectSortKeys, opts.Sort) {
+		return fmt.Errorf("invalid --sort %q: expected one of %v", opts.Sort, strings.Join(projectSortKeys, ", "))
+	}
+	if opts.Limit < 0 {
+		return fmt.Errorf("invalid --limit %v: expected 0 or more", opts.Limit)
+	}
+	if opts.GroupBy != "" && opts.Print0 {
+		return fmt.Errorf("--group-by cannot be combined with --print0")
+	}
+	if opts.Format != "default" && (opts.Print0 || opts.GroupBy != "") {
+		return fmt.Errorf("--format %v cannot be combined with --print0 or --group-by", opts.Format)
+	}
+	if opts.Duplicates && (opts.Format != "default" || opts.Print0 || opts.GroupBy != "" || opts.Sort != "path" || opts.Limit > 0) {
+		return fmt.Errorf("--duplicates cannot be combined with --format, --print0, --group-by, --sort or --limit")
+	}
+
+	projects, err := findProjects(ctx, runner)
+	if err != nil {
+		return err
+	}
+	if opts.Org != "" {
+		projects = filterProjectsByOrg(projects, opts.Org)
+	}
+	if opts.Dirty {
+		if projects, err = filterDirtyProjects(ctx, runner, projects); err != nil {
+			return err
+		}
+	}
+	if opts.Duplicates {
+		w := bufio.NewWriter(os.Stdout)
+		if err := printDuplicateProjects(ctx, w, projects); err != nil {
+			return err
+		}
+		return w.Flush()
+	}
+	if opts.Sort == "recent" {
+		sortProjectsByRecent(projects)
+	}
+	if opts.Limit > 0 && len(projects) > opts.Limit {
+		projects = projects[:opts.Limit]
+	}
+
+	return writeProjects(projects, opts)
+}
+
+// checkProjectsFormat validates opts.Format, turning "null" into Print0.
+func checkProjectsFormat(opts *projectsOptions) error {
+	switch opts.Format {
+	case "default", "json":
+	case "null":
+		opts.Format, opts.Print0 = "default", true
+	default:
+		return fmt.Errorf("invalid --format %q: expected default, json or null", opts.Format)
+	}
+	return nil
+}
+
+// writeProjects prints projects to stdout in the format opts asks for.
+func writeProjects(projects []string, opts projectsOptions) error {
+	w := bufio.NewWriter(os.Stdout)
+	defer w.Flush()
+
+	if opts.GroupBy == "repo" {
+		printProjectsByRepo(w, projects)
+		return w.Flush()
+	}
+
+	if opts.Format == "json" {
+		out := make([]projectJSON, 0, len(projects))
+		for _, p := range projects {
+			remote, err := gitRemoteURL(p)
+			if err != nil {
+				log.Debugf("error reading remote of %v: %v", p, err)
+			}
+			branch, err := gitBranch(p)
+			if err != nil {
+				log.Debugf("error reading branch of %v: %v", p, err)
+			}
+			out = append(out, projectJSON{
+				Path:        p,
+				Remote:      remote,
+				Branch:      branch,
+				Worktree:    isGitWorktree(p),
+				Bare:        isBareGitRepo(p),
+				Identifiers: append([]manifest.Identifier{}, manifest.Identifiers(p)...),
+			})
+		}
+		if err := writeJSON(w, out, opts.Pretty); err != nil {
+			return err
+		}
+		return w.Flush()
+	}
+
+	if opts.Print0 {
+		for _, p := range projects {
+			fmt.Fprint(w, p, "\x00")
+		}
+		return w.Flush()
+	}
+
+	width := outputWidth()
+	for _, p := range projects {
+		fmt.Fprintln(w, truncateMiddle(p, width))
+	}
+	return w.Flush()
+}
+
+// filterProjectsByOrg returns the projects whose remote is owned by org,
+// compared case-insensitively.
+func filterProjectsByOrg(projects []string, org string) []string {
+	var kept []string
+	for _, p := range projects {
+		remote, err := gitRemoteURL(p)
+		if err != nil {
+			log.Debugf("error reading remote of %v: %v", p, err)
+		}
+		if strings.EqualFold(repoOwner(repoIdentity(remote)), org) {
+			kept = append(kept, p)
+		}
+	}
+	return kept
+}
+
+// filterDirtyProjects returns the projects with uncommitted changes, in
+// their original order, and those whose status cannot be read, as they may
+// have changes too.
+func filterDirtyProjects(ctx context.Context, r execx.Runner, projects []string) ([]string, error) {
+	dirty, unknown, err := projectStatuses(ctx, r, projects)
+	if err != nil {
+		return nil, err
+	}
+
+	var kept []string
+	for _, p := range projects {
+		if dirty[p] || unknown[p] {
+			kept = append(kept, p)
+		}
+	}
+	return kept, nil
+}
+
+// projectStatuses runs git status for several projects at a time and
+// returns those with uncommitted changes, and those whose status cannot be
+// read, with a warning for each. It fails if git is not installed.
+func projectStatuses(ctx context.Context, r execx.Runner, projects []string) (dirty, unknown map[string]bool, err error) {
+	isDirty := make([]bool, len(projects))
+	errs := make([]error, len(projects))
+	forEachParallel(len(projects), func(i int) {
+		isDirty[i], errs[i] = gitDirty(ctx, r, projects[i])
+	})
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	dirty, unknown = map[string]bool{}, map[string]bool{}
+	for i, p := range projects {
+		switch {
+		case errors.Is(errs[i], errGitNotFound):
+			return nil, nil, errs[i]
+		case errs[i] != nil:
+			log.Warnf("cannot tell whether %v has uncommitted changes: %v", tildePath(p), errs[i])
+			unknown[p] = true
+		case isDirty[i]:
+			dirty[p] = true
+		}
+	}
+	return dirty, unknown, nil
+}
+
+// sortProjectsByRecent sorts projects most recently used first, keeping
+// the natural order among equals.
+func sortProjectsByRecent(projects []string) {
+	used := make(map[string]time.Time, len(projects))
+	for _, p := range projects {
+		t, err := gitLastUsed(p)
+		if err != nil {
+			log.Debugf("error reading last use of %v: %v", p, err)
+		}
+		used[p] = t
+	}
+	sort.SliceStable(projects, func(i, j int) bool {
+		return used[projects[i]].After(used[projects[j]])
+	})
+}
+
+// groupProjectsByRepo groups the projects by repository identity. It
+// returns the identities in natural order, the projects of each, and the
+// projects without a remote.
+func groupProjectsByRepo(projects []string) ([]string, map[string][]string, []string) {
+	groups := map[string][]string{}
+	var identities, unlinked []string
+
+	for _, p := range projects {
+		remote, err := gitRemoteURL(p)
+		if err != nil {
+			log.Debugf("error reading remote of %v: %v", p, err)
+		}
+
+		id := repoIdentity(remote)
+		if id == "" {
+			unlinked = append(unlinked, p)
+			continue
+		}
+		if _, ok := groups[id]; !ok {
+			identities = append(identities, id)
+		}
+		groups[id] = append(groups[id], p)
+	}
+
+	sort.Slice(identities, func(i, j int) bool {
+		return collateLess(identities[i], identities[j])
+	})
+	return identities, groups, unlinked
+}
+
+// printProjectsByRepo prints the projects to w grouped by repository
+// identity, groups in natural order and repositories without a remote last.
+func printProjectsByRepo(w io.Writer, projects []string) {
+	identities, groups, unlinked := groupProjectsByRepo(projects)
+
+	width := outputWidth()
+	for _, id := range identities {
+		fmt.Fprintln(w, truncateMiddle(id, width))
+		for _, p := range groups[id] {
+			fmt.Fprintln(w, "  "+truncateMiddle(p, width-2))
+		}
+	}
+	if len(unlinked) > 0 {
+		fmt.Fprintln(w, "(no remote)")
+		for _, p := range unlinked {
+			fmt.Fprintln(w, "  "+truncateMiddle(p, width-2))
+		}
+	}
+}
+
+// printDuplicateProjects prints to w the repositories checked out more
+// than once, each checkout with when it was last used and whether it has
+// uncommitted changes. The most recently used clean checkout of each is
+// marked as the one to keep; checkouts whose status cannot be read are
+// never kept.
+func printDuplicateProjects(ctx context.Context, w io.Writer, projects []string) error {
+	identities, groups, _ := groupProjectsByRepo(projects)
+
+	var duplicated []string
+	for _, id := range identities {
+		if len(groups[id]) > 1 {
+			duplicated = append(duplicated, groups[id]...)
+		}
+	}
+	dirty, unknown, err := projectStatuses(ctx, runner, duplicated)
+	if err != nil {
+		return err
+	}
+
+	width := outputWidth()
+	for _, id := range identities {
+		checkouts := groups[id]
+		if len(checkouts) < 2 {
+			continue
+		}
+
+		used := map[string]time.Time{}
+		keep := ""
+		for _, p := range checkouts {
+			t, err := gitLastUsed(p)
+			if err != nil {
+				log.Debugf("error reading last use of %v: %v", p, err)
+			}
+			used[p] = t
+			if !dirty[p] && !unknown[p] && (keep == "" || t.After(used[keep])) {
+				keep = p
+			}
+		}
+
+		fmt.Fprintln(w, truncateMiddle(id, width))
+		for _, p := range checkouts {
+			notes := []string{"last used " + used[p].Local().Format("2006-01-02 15:04")}
+			if dirty[p] {
+				notes = append(notes, "dirty")
+			}
+			if unknown[p] {
+				notes = append(notes, "status unknown")
+			}
+			if p == keep {
+				notes = append(notes, "keep")
+			}
+			annotation := " (" + strings.Join(notes, ", ") + ")"
+			fmt.Fprintln(w, "  "+truncateMiddle(p, width-2-len(annotation))+annotation)
+		}
+	}
+	return nil
 }
@@ -0,0 +1,176 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shalomb/gum/internal/execx"
+)
+
+// gitNotFound is the error of running git when it is not installed.
+var gitNotFound = &exec.Error{Name: "git", Err: exec.ErrNotFound}
+
+func TestFilterDirtyProjects(t *testing.T) {
+	r := &execx.Fake{Responses: []execx.FakeResponse{
+		{Argv: []string{"git", "status"}, Dir: "/clean"},
+		{Argv: []string{"git", "status"}, Dir: "/dirty", Stdout: " M file\n"},
+		{Argv: []string{"git", "status"}, Dir: "/broken", Err: errors.New("exit status 128")},
+	}}
+
+	got, err := filterDirtyProjects(context.Background(), r, []string{"/broken", "/clean", "/dirty"})
+	if err != nil {
+		t.Fatalf("filterDirtyProjects failed: %v", err)
+	}
+	// A repository whose status cannot be read may be dirty.
+	if want := []string{"/broken", "/dirty"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("filterDirtyProjects = %q, want %q", got, want)
+	}
+}
+
+func TestFilterDirtyProjectsWithoutGit(t *testing.T) {
+	r := &execx.Fake{Responses: []execx.FakeResponse{{Argv: []string{"git"}, Err: gitNotFound}}}
+
+	if got, err := filterDirtyProjects(context.Background(), r, []string{"/a", "/b"}); !errors.Is(err, errGitNotFound) {
+		t.Errorf("filterDirtyProjects without git = %q, %v; want %v", got, err, errGitNotFound)
+	}
+}
+
+func TestPrintDuplicateProjectsNeverKeepsUnknown(t *testing.T) {
+	root := t.TempDir()
+	older, newer := filepath.Join(root, "older"), filepath.Join(root, "newer")
+	for i, repo := range []string{older, newer} {
+		writeFile(t, filepath.Join(repo, ".git", "config"), "[remote \"origin\"]\n\turl = https://example.com/me/repo\n")
+		writeFile(t, filepath.Join(repo, ".git", "HEAD"), "ref: refs/heads/main\n")
+		used := time.Date(2023, 1, 1+i, 0, 0, 0, 0, time.UTC)
+		if err := chtimes(filepath.Join(repo, ".git", "HEAD"), used); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	saved := runner
+	t.Cleanup(func() { runner = saved })
+	runner = &execx.Fake{Responses: []execx.FakeResponse{
+		{Argv: []string{"git", "status"}, Dir: older},
+		{Argv: []string{"git", "status"}, Dir: newer, Err: errors.New("exit status 128")},
+	}}
+	Width = 200
+	t.Cleanup(func() { Width = 0 })
+
+	var out bytes.Buffer
+	if err := printDuplicateProjects(context.Background(), &out, []string{newer, older}); err != nil {
+		t.Fatalf("printDuplicateProjects failed: %v", err)
+	}
+	for _, line := range strings.Split(out.String(), "\n") {
+		switch {
+		case strings.Contains(line, newer) && (!strings.Contains(line, "status unknown") || strings.Contains(line, "keep")):
+			t.Errorf("checkout with unknown status listed as %q", line)
+		case strings.Contains(line, older) && !strings.HasSuffix(line, ", keep)"):
+			t.Errorf("clean checkout listed as %q, want it kept", line)
+		}
+	}
+
+	runner = &execx.Fake{Responses: []execx.FakeResponse{{Argv: []string{"git"}, Err: gitNotFound}}}
+	if err := printDuplicateProjects(context.Background(), &out, []string{newer, older}); !errors.Is(err, errGitNotFound) {
+		t.Errorf("printDuplicateProjects without git returned %v, want %v", err, errGitNotFound)
+	}
+}
+
+func TestProjectsPrint0(t *testing.T) {
+	home := withHome(t)
+	withoutLocate(t)
+	withProjects(t, "~/src")
+	var want []string
+	for _, name := range []string{"a repo", "b\nrepo", "c"} {
+		repo := filepath.Join(home, "src", name)
+		if err := os.MkdirAll(filepath.Join(repo, ".git"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		want = append(want, repo)
+	}
+
+	for _, args := range [][]string{{"--print0"}, {"-0"}, {"--format", "null"}, {"-0", "--sort", "recent", "--limit", "2"}, {"-f", "null", "--sort", "recent", "-n", "2"}} {
+		out := runGum(t, append([]string{"projects"}, args...)...)
+		records := want
+		if len(args) > 2 {
+			records = nil
+			for _, record := range strings.SplitAfter(out, "\x00") {
+				if record != "" {
+					records = append(records, strings.TrimSuffix(record, "\x00"))
+				}
+			}
+			if len(records) != 2 {
+				t.Errorf("gum projects %q printed %q, want 2 records", args, out)
+			}
+		}
+		checkRecords(t, out, records)
+	}
+}
+
+func TestProjectsGroupByRepo(t *testing.T) {
+	home := withHome(t)
+	withoutLocate(t)
+	withProjects(t, "~/src")
+	t.Cleanup(func() { Width = 0 })
+
+	src := filepath.Join(home, "src")
+	remotes := map[string]string{
+		"https/gum":   "https://github.com/shalomb/gum",
+		"dotgit/gum":  "https://github.com/shalomb/gum.git",
+		"scp/gum":     "git@github.com:shalomb/gum.git",
+		"ssh/gum":     "ssh://git@github.com/shalomb/gum",
+		"https/other": "https://github.com/shalomb/other.git",
+		"local/notes": "",
+	}
+	for repo, remote := range remotes {
+		config := ""
+		if remote != "" {
+			config = "[remote \"origin\"]\n\turl = " + remote + "\n"
+		}
+		writeFile(t, filepath.Join(src, repo, ".git", "config"), config)
+		writeFile(t, filepath.Join(src, repo, ".git", "HEAD"), "ref: refs/heads/main\n")
+	}
+
+	checkouts := []string{"  " + src + "/dotgit/gum", "  " + src + "/https/gum", "  " + src + "/scp/gum", "  " + src + "/ssh/gum"}
+	want := append(append([]string{"github.com/shalomb/gum"}, checkouts...),
+		"github.com/shalomb/other", "  "+src+"/https/other",
+		"(no remote)", "  "+src+"/local/notes")
+	for _, width := range []int{0, 200} {
+		Width = width
+		out := runGum(t, "projects", "--group-by", "repo")
+		if got := strings.Split(strings.TrimSuffix(out, "\n"), "\n"); !reflect.DeepEqual(got, want) {
+			t.Errorf("gum projects --group-by repo at width %v printed\n%q\nwant\n%q", width, got, want)
+		}
+	}
+
+	// --duplicates groups the same way, and only lists the repository
+	// checked out more than once.
+	out := runGum(t, "projects", "--duplicates")
+	lines := strings.Split(strings.TrimSuffix(out, "\n"), "\n")
+	if len(lines) != 5 || lines[0] != "github.com/shalomb/gum" {
+		t.Fatalf("gum projects --duplicates printed %q, want one heading and 4 checkouts", out)
+	}
+	for i, checkout := range checkouts {
+		if !strings.HasPrefix(lines[i+1], checkout+" (") {
+			t.Errorf("gum projects --duplicates listed %q, want %v", lines[i+1], checkout)
+		}
+	}
+
+	// Other formats do not group, and say so rather than ignore it.
+	for _, format := range []string{"json", "null"} {
+		err := doProjects(context.Background(), projectsOptions{Format: format, GroupBy: "repo", Sort: "path"})
+		if err == nil || !strings.Contains(err.Error(), "--group-by") {
+			t.Errorf("--format %v --group-by repo returned %v, want it refused", format, err)
+		}
+	}
+	if err := doProjects(context.Background(), projectsOptions{Format: "default", Print0: true, GroupBy: "repo", Sort: "path"}); err == nil {
+		t.Errorf("--print0 --group-by repo succeeded, want it refused")
+	}
+}
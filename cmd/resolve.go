@@ -0,0 +1,108 @@
+/*
+Copyright © 2023 shalomb <s.bhooshi@gmail.com>
+*/
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// resolveCmd represents the resolve command
+var resolveCmd = &cobra.Command{
+	Use:   "resolve [line]",
+	Short: "Print the path a line of gum output refers to",
+	Long: `Print the absolute path that a line of gum output refers to, so that
+pickers such as fzf can hand back the whole selected line:
+
+  cd "$(gum dirs | fzf | gum resolve)"
+
+The line is taken from the argument or else the first line of stdin. It
+may be a "gum dirs" line, a "path (annotation)" line, an indented line of
+a grouped listing, a plain path, or an object of --format json output on
+a line of its own, as jq -c '.[]' prints them, with "~" standing for the
+home directory. gum resolve fails unless the line names a project, as
+gum projects lists them, or a directory in use, as gum dirs lists them.`,
+	Args:         cobra.MaximumNArgs(1),
+	SilenceUsage: true,
+
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var line string
+		if len(args) > 0 {
+			line = args[0]
+		} else {
+			scanner := bufio.NewScanner(os.Stdin)
+			scanner.Buffer(nil, 1<<20)
+			if scanner.Scan() {
+				line = scanner.Text()
+			}
+			if err := scanner.Err(); err != nil {
+				return fmt.Errorf("error reading stdin: %w", err)
+			}
+		}
+
+		path, err := doResolve(cmd.Context(), line)
+		if err != nil {
+			return err
+		}
+		fmt.Println(path)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(resolveCmd)
+}
+
+// doResolve returns the first candidate path of line that is a project or
+// a directory in use.
+func doResolve(ctx context.Context, line string) (string, error) {
+	if strings.TrimSpace(line) == "" {
+		return "", fmt.Errorf("nothing to resolve")
+	}
+
+	var paths []string
+	for _, candidate := range lineCandidates(line) {
+		if path := expandPath(candidate); filepath.IsAbs(path) {
+			paths = append(paths, path)
+		}
+	}
+	if len(paths) == 0 {
+		return "", fmt.Errorf("%q does not refer to a path", line)
+	}
+
+	// Directories in use are quicker to list than projects, so look
+	// there first.
+	sightings := sampleDirs(ctx)
+	for _, path := range paths {
+		if sightings[path] > 0 {
+			return path, nil
+		}
+	}
+
+	projects, err := findProjects(ctx, runner)
+	if err != nil {
+		return "", err
+	}
+	known := map[string]bool{}
+	for _, p := range projects {
+		known[p] = true
+		// gum dirs lists projects with symlinks resolved.
+		if real, err := filepath.EvalSymlinks(p); err == nil {
+			known[real] = true
+		}
+	}
+	for _, path := range paths {
+		if known[path] {
+			return path, nil
+		}
+	}
+
+	return "", fmt.Errorf("%q does not refer to a known project or a directory in use", line)
+}
@@ -0,0 +1,172 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/shalomb/gum/internal/procdirs"
+)
+
+// workIn starts a process working in dir for the rest of the test, so that
+// gum dirs sees dir in use. It skips the test where working directories
+// cannot be listed.
+func workIn(t *testing.T, dir string) {
+	t.Helper()
+	if _, err := procdirs.Cwds(context.Background(), runner); errors.Is(err, procdirs.ErrUnsupported) {
+		t.Skip(err)
+	}
+	cmd := exec.Command("sleep", "600")
+	cmd.Dir = dir
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	})
+}
+
+// resolveFixture makes a home with two projects, one with a space in its
+// name, and two directories in use: one inside a project and one outside
+// every project. It returns the projects and the dirs entries gum lists.
+func resolveFixture(t *testing.T) ([]string, map[string]dirEntry) {
+	t.Helper()
+	home := withHome(t)
+	withoutLocate(t)
+	withProjects(t, "~/my projects")
+
+	alpha := filepath.Join(home, "my projects", "alpha")
+	beta := filepath.Join(home, "my projects", "beta gamma")
+	work := filepath.Join(home, "work dir")
+	src := filepath.Join(alpha, "src")
+	for _, dir := range []string{filepath.Join(alpha, ".git"), filepath.Join(beta, ".git"), work, src} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeFile(t, filepath.Join(alpha, ".git", "config"), "[remote \"origin\"]\n\turl = git@github.com:shalomb/alpha.git\n")
+	workIn(t, work)
+	workIn(t, src)
+
+	dirs := map[string]dirEntry{
+		alpha: {Path: alpha, Frequency: 3, Project: alpha},
+		src:   {Path: src, Frequency: 2, Project: alpha},
+		work:  {Path: work, Frequency: 1},
+	}
+	return []string{alpha, beta}, dirs
+}
+
+// outputLines splits the output of a listing into the lines a picker
+// would hand back: records for NUL-terminated output, one object per
+// line for JSON, and lines otherwise.
+func outputLines(t *testing.T, out, format string) []string {
+	t.Helper()
+	switch format {
+	case "null":
+		return strings.Split(strings.TrimSuffix(out, "\x00"), "\x00")
+	case "json":
+		var objects []json.RawMessage
+		if err := json.Unmarshal([]byte(out), &objects); err != nil {
+			t.Fatalf("invalid JSON %q: %v", out, err)
+		}
+		var lines []string
+		for _, o := range objects {
+			var line bytes.Buffer
+			if err := json.Compact(&line, o); err != nil {
+				t.Fatal(err)
+			}
+			lines = append(lines, line.String())
+		}
+		return lines
+	}
+	return strings.Split(strings.TrimSuffix(out, "\n"), "\n")
+}
+
+func TestResolveRoundTrip(t *testing.T) {
+	projects, dirs := resolveFixture(t)
+	var dirPaths []string
+	for path := range dirs {
+		dirPaths = append(dirPaths, path)
+	}
+
+	tests := []struct {
+		name   string
+		format string
+		emit   func() error
+		want   []string
+	}{
+		{"projects", "default", func() error { return writeProjects(projects, projectsOptions{Format: "default"}) }, projects},
+		{"projects null", "null", func() error { return writeProjects(projects, projectsOptions{Format: "default", Print0: true}) }, projects},
+		{"projects json", "json", func() error { return writeProjects(projects, projectsOptions{Format: "json"}) }, projects},
+		{"projects json pretty", "json", func() error { return writeProjects(projects, projectsOptions{Format: "json", Pretty: true}) }, projects},
+		{"projects by repo", "grouped", func() error { return writeProjects(projects, projectsOptions{Format: "default", GroupBy: "repo"}) }, projects},
+		{"dirs", "default", func() error { return renderDirs(dirs, dirsOptions{Format: "default"}) }, dirPaths},
+		{"dirs annotated", "default", func() error { return renderDirs(dirs, dirsOptions{Format: "default", Annotate: true}) }, dirPaths},
+		{"dirs null", "null", func() error { return renderDirs(dirs, dirsOptions{Format: "default", Print0: true}) }, dirPaths},
+		{"dirs json", "json", func() error { return renderDirs(dirs, dirsOptions{Format: "json"}) }, dirPaths},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := captureStdout(t, func() {
+				if err := tt.emit(); err != nil {
+					t.Errorf("emitting failed: %v", err)
+				}
+			})
+
+			var got []string
+			for _, line := range outputLines(t, out, tt.format) {
+				// Group headings name repositories, not paths.
+				if tt.format == "grouped" && !strings.HasPrefix(line, "  ") {
+					continue
+				}
+				path, err := doResolve(context.Background(), line)
+				if err != nil {
+					t.Errorf("doResolve(%q) failed: %v", line, err)
+					continue
+				}
+				got = append(got, path)
+			}
+
+			want := append([]string(nil), tt.want...)
+			sort.Strings(got)
+			sort.Strings(want)
+			if strings.Join(got, "\n") != strings.Join(want, "\n") {
+				t.Errorf("resolved %q, want %q in\n%s", got, want, out)
+			}
+		})
+	}
+}
+
+func TestResolveRefusesUnknownPaths(t *testing.T) {
+	projects, _ := resolveFixture(t)
+	unused := t.TempDir()
+
+	for _, line := range []string{
+		"",
+		"   ",
+		"garbage",
+		unused,
+		"7\t" + unused,
+		unused + " (annotation)",
+		`{"path":"` + unused + `"}`,
+		filepath.Join(projects[0], "missing"),
+		filepath.Dir(projects[0]),
+	} {
+		if path, err := doResolve(context.Background(), line); err == nil {
+			t.Errorf("doResolve(%q) = %q, want an error", line, path)
+		}
+	}
+
+	if path, err := doResolve(context.Background(), "~/my projects/beta gamma"); err != nil || path != projects[1] {
+		t.Errorf("doResolve of a ~ path = %q, %v; want %q", path, err, projects[1])
+	}
+}
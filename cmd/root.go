@@ -6,8 +6,14 @@ Copyright © 2023 shalomb <s.bhooshi@gmail.com>
 */
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"os"
+	"os/exec"
+	"os/signal"
 
+	"github.com/shalomb/gum/internal/execx"
 	"github.com/spf13/cobra"
 	// "github.com/spf13/viper"
 )
@@ -15,6 +21,24 @@ import (
 var (
 	// Debug Enable debugging
 	Debug bool
+
+	// SortCaseSensitive Sort paths by byte value rather than case-insensitively
+	SortCaseSensitive bool
+
+	// Width Number of columns to fit listings in (default: terminal width)
+	Width int
+
+	// NonInteractive Never wait for user input (see isInteractive)
+	NonInteractive bool
+
+	// Jobs Number of tasks to run at once (default: number of CPUs)
+	Jobs int
+
+	// NoLocate Always search the file system for projects
+	NoLocate bool
+
+	// runner runs the external programs gum shells out to
+	runner execx.Runner = execx.Exec{}
 )
 
 // rootCmd represents the base command when called without any subcommands
@@ -35,8 +59,24 @@ to quickly create a Cobra application.`,
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
-	err := rootCmd.Execute()
+	initConfig()
+	addCompletionInstall()
+	if err := registerAliases(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: invalid commands in config: %v\n", err)
+		os.Exit(1)
+	}
+
+	// Cancel running searches and git commands on Ctrl-C.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	err := rootCmd.ExecuteContext(ctx)
 	if err != nil {
+		// Exit as a failed git or other program did, for scripts.
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() > 0 {
+			os.Exit(exitErr.ExitCode())
+		}
 		os.Exit(1)
 	}
 }
@@ -46,6 +86,12 @@ func init() {
 	// Cobra supports persistent flags, which, if defined here,
 	// will be global for your application.
 
+	rootCmd.PersistentFlags().IntVar(&Width, "width", 0, "Fit listings in this many columns (default: the terminal width, no limit when piped)")
+	rootCmd.PersistentFlags().BoolVar(&SortCaseSensitive, "sort-case-sensitive", false, "Sort paths case-sensitively (upper case first)")
+	rootCmd.PersistentFlags().IntVarP(&Jobs, "jobs", "j", 0, "Run this many searches or git commands at once (default: the number of CPUs)")
+	rootCmd.PersistentFlags().BoolVar(&NoLocate, "no-locate", false, "Search the file system for projects even if the locate database is fresh (it misses repositories created since updatedb ran, unless next to known ones)")
+	rootCmd.PersistentFlags().BoolVar(&NonInteractive, "non-interactive", false, "Never wait for input, as when stdin is not a terminal (also $GUM_NON_INTERACTIVE)")
+
 	// rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.gum.yaml)")
 
 	// Cobra also supports local flags, which will only run
@@ -0,0 +1,125 @@
+/*
+Copyright © 2023 shalomb <s.bhooshi@gmail.com>
+*/
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/shalomb/gum/internal/fuzzy"
+	"github.com/spf13/cobra"
+)
+
+// similarCmd represents the similar command
+var similarCmd = &cobra.Command{
+	Use:   "similar <query>",
+	Short: "List the projects whose names resemble a query",
+	Long: `List the projects whose names best match a query, best first, for when
+you only half remember what a repository is called:
+
+  cd "$(gum similar gmu -n1)"
+
+Projects are matched by directory name and by the names their package
+manifests declare (go.mod, package.json, Cargo.toml, pyproject.toml).
+Names containing the query rank highest, along with names whose words
+start with the pieces of the query, as in "pdp" or "data-pipe" for
+platform-data-pipeline, then names a few typos away. Matches scoring
+below --threshold, from 0 to 1, are dropped. The output formats are those
+of gum projects. --explain-match prints how every project scored to
+stderr.`,
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var opts projectsOptions
+		opts.Print0, _ = cmd.Flags().GetBool("print0")
+		opts.Format, _ = cmd.Flags().GetString("format")
+		opts.Pretty, _ = cmd.Flags().GetBool("pretty")
+		opts.Limit, _ = cmd.Flags().GetInt("limit")
+		threshold, _ := cmd.Flags().GetFloat64("threshold")
+		explain, _ := cmd.Flags().GetBool("explain-match")
+		return doSimilar(cmd.Context(), args[0], threshold, explain, opts)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(similarCmd)
+
+	similarCmd.Flags().IntP("limit", "n", 10, "List at most this many projects (0 for all)")
+	similarCmd.Flags().Float64("threshold", 0.5, "Drop matches scoring below this, from 0 to 1")
+	similarCmd.Flags().StringP("format", "f", "default", "Output format (default, json, null)")
+	similarCmd.Flags().Bool("pretty", false, "Indent JSON output")
+	similarCmd.Flags().Bool("explain-match", false, "Print the score components of every project to stderr")
+	similarCmd.Flags().BoolP("print0", "0", false, "Print each path terminated by a NUL byte (for xargs -0, fzf --read0)")
+}
+
+func doSimilar(ctx context.Context, query string, threshold float64, explain bool, opts projectsOptions) error {
+	if err := checkProjectsFormat(&opts); err != nil {
+		return err
+	}
+	if opts.Format != "default" && opts.Print0 {
+		return fmt.Errorf("--format %v cannot be combined with --print0", opts.Format)
+	}
+	if threshold < 0 || threshold > 1 {
+		return fmt.Errorf("invalid --threshold %v: expected a number from 0 to 1", threshold)
+	}
+	if opts.Limit < 0 {
+		return fmt.Errorf("invalid --limit %v: expected 0 or more", opts.Limit)
+	}
+
+	projects, err := findProjects(ctx, runner)
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer
+	if explain {
+		w = os.Stderr
+	}
+	return writeProjects(rankSimilar(query, projects, threshold, opts.Limit, w), opts)
+}
+
+// rankSimilar returns up to limit projects, or all if limit is 0, whose
+// names score at least threshold against query, best first and in natural
+// order among equals. If explain is not nil, the score components of every
+// project are written to it, best first.
+func rankSimilar(query string, projects []string, threshold float64, limit int, explain io.Writer) []string {
+	scores := map[string]fuzzy.Match{}
+	matched := map[string]string{}
+	for _, p := range projects {
+		for _, name := range projectNames(p) {
+			if m := fuzzy.Explain(query, name); m.Score > scores[p].Score || matched[p] == "" {
+				scores[p], matched[p] = m, name
+			}
+		}
+	}
+
+	ranked := append([]string{}, projects...)
+	sort.Slice(ranked, func(i, j int) bool {
+		if si, sj := scores[ranked[i]].Score, scores[ranked[j]].Score; si != sj {
+			return si > sj
+		}
+		return collateLess(ranked[i], ranked[j])
+	})
+
+	var matches []string
+	for _, p := range ranked {
+		m := scores[p]
+		if explain != nil {
+			fmt.Fprintf(explain, "%.2f\texact=%.2f substring=%.2f words=%.2f edit=%.2f name=%q\t%v\n",
+				m.Score, m.Exact, m.Substring, m.Words, m.Edit, matched[p], p)
+		}
+		if m.Score >= threshold && m.Score > 0 {
+			matches = append(matches, p)
+		}
+	}
+
+	if limit > 0 && len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches
+}
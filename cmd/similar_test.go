@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRankSimilar(t *testing.T) {
+	// Deliberately out of order, so that ties must be put in order.
+	projects := []string{
+		"/w/hugo", "/w/b/gum", "/w/gum-cli", "/w/yum", "/w/a/gum", "/w/platform-data",
+		"/w/go-utils-misc", "/w/gmu", "/w/drum", "/w/Gum",
+	}
+
+	tests := []struct {
+		name      string
+		threshold float64
+		limit     int
+		want      []string
+	}{
+		{"default threshold", 0.5, 0, []string{
+			"/w/a/gum", "/w/b/gum", "/w/Gum", "/w/go-utils-misc", "/w/gum-cli", "/w/gmu", "/w/yum", "/w/drum",
+		}},
+		{"higher threshold", 0.6, 0, []string{
+			"/w/a/gum", "/w/b/gum", "/w/Gum", "/w/go-utils-misc", "/w/gum-cli", "/w/gmu", "/w/yum",
+		}},
+		{"exact only", 1, 0, []string{"/w/a/gum", "/w/b/gum", "/w/Gum"}},
+		{"no threshold", 0, 0, []string{
+			"/w/a/gum", "/w/b/gum", "/w/Gum", "/w/go-utils-misc", "/w/gum-cli", "/w/gmu", "/w/yum", "/w/drum",
+			"/w/hugo", "/w/platform-data",
+		}},
+		{"limit within a tie", 0.5, 2, []string{"/w/a/gum", "/w/b/gum"}},
+		{"limit after ties", 0.5, 6, []string{
+			"/w/a/gum", "/w/b/gum", "/w/Gum", "/w/go-utils-misc", "/w/gum-cli", "/w/gmu",
+		}},
+		{"limit above matches", 0.6, 20, []string{
+			"/w/a/gum", "/w/b/gum", "/w/Gum", "/w/go-utils-misc", "/w/gum-cli", "/w/gmu", "/w/yum",
+		}},
+	}
+	for _, tt := range tests {
+		var explain strings.Builder
+		got := rankSimilar("gum", projects, tt.threshold, tt.limit, &explain)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%v: rankSimilar = %q, want %q", tt.name, got, tt.want)
+		}
+
+		// Every project is explained, best first, whatever is listed.
+		lines := strings.Split(strings.TrimSuffix(explain.String(), "\n"), "\n")
+		if len(lines) != len(projects) {
+			t.Errorf("%v: explained %v projects, want %v", tt.name, len(lines), len(projects))
+		}
+		if !strings.HasPrefix(lines[0], "1.00\t") || !strings.HasSuffix(lines[0], "\t/w/a/gum") {
+			t.Errorf("%v: explained %q first, want /w/a/gum scoring 1", tt.name, lines[0])
+		}
+	}
+}
+
+func TestDoSimilar(t *testing.T) {
+	home := withHome(t)
+	withoutLocate(t)
+	withProjects(t, "~/src")
+	for _, name := range []string{"work/gum", "oss/gum", "gum-cli", "gmu", "hugo", "platform-data"} {
+		if err := os.MkdirAll(filepath.Join(home, "src", name, ".git"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	src := filepath.Join(home, "src")
+
+	tests := []struct {
+		limit int
+		want  []string
+	}{
+		{0, []string{src + "/oss/gum", src + "/work/gum", src + "/gum-cli", src + "/gmu"}},
+		{3, []string{src + "/oss/gum", src + "/work/gum", src + "/gum-cli"}},
+		{1, []string{src + "/oss/gum"}},
+	}
+	for _, tt := range tests {
+		out := captureStdout(t, func() {
+			if err := doSimilar(context.Background(), "gum", 0.5, false, projectsOptions{Format: "default", Limit: tt.limit}); err != nil {
+				t.Errorf("doSimilar failed: %v", err)
+			}
+		})
+		if got := strings.Split(strings.TrimSuffix(out, "\n"), "\n"); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("doSimilar with limit %v printed %q, want %q", tt.limit, got, tt.want)
+		}
+	}
+
+	for _, opts := range []struct {
+		threshold float64
+		limit     int
+	}{{-0.1, 0}, {1.5, 0}, {0.5, -1}} {
+		if err := doSimilar(context.Background(), "gum", opts.threshold, false, projectsOptions{Format: "default", Limit: opts.limit}); err == nil {
+			t.Errorf("doSimilar with threshold %v and limit %v succeeded, want an error", opts.threshold, opts.limit)
+		}
+	}
+}
@@ -6,13 +6,24 @@ Copyright © 2023 NAME HERE <EMAIL ADDRESS>
 */
 
 import (
+	"context"
 	"fmt"
+	"io/fs"
+	"os"
 	"os/exec"
-	"os/user"
 	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/adrg/xdg"
+	"github.com/shalomb/gum/internal/execx"
+	"github.com/shalomb/gum/internal/fuzzy"
+	"github.com/shalomb/gum/internal/locate"
+	"github.com/shalomb/gum/internal/manifest"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
@@ -25,7 +36,7 @@ var updateCmd = &cobra.Command{
 	Long:  `Update the database`,
 
 	RunE: func(cmd *cobra.Command, args []string) error {
-		if err := doUpdate(); err != nil {
+		if err := doUpdate(cmd.Context()); err != nil {
 			return err
 		}
 		return nil
@@ -48,6 +59,13 @@ func init() {
 	updateCmd.Flags().BoolP("dirs", "d", false, "Update dirs")
 
 	viper.SetDefault("CacheDir", xdg.CacheHome)
+	viper.SetDefault("discovery.worktrees", true)
+}
+
+// initConfig reads the config file. It is called by Execute rather than
+// from init so that the package can be loaded, as by its tests, without
+// one.
+func initConfig() {
 	viper.SetConfigName("config")
 	viper.SetConfigType("yaml")
 	viper.AddConfigPath(filepath.Join(xdg.ConfigHome, "gum"))
@@ -60,7 +78,7 @@ func init() {
 	}
 }
 
-func doUpdate() error {
+func doUpdate(ctx context.Context) error {
 	fmt.Printf("doUpdate called")
 	for key, value := range viper.GetViper().AllSettings() {
 		log.WithFields(log.Fields{
@@ -68,38 +86,514 @@ func doUpdate() error {
 		}).Info("\nCommand Flag")
 	}
 
-	curUser, _ := user.Current()
-	homeDir := curUser.HomeDir
+	projects, err := findProjects(ctx, runner)
+	if err != nil {
+		return err
+	}
+
+	result := make([]string, 0, len(projects))
+	for _, p := range projects {
+		result = append(result, tildePath(p))
+	}
+
+	fmt.Printf("%v", result)
+	// return fmt.Errorf("Heh")
+	return nil
+}
+
+// findProjects returns the absolute paths of the git repositories found
+// under the configured project directories, searched in parallel, in
+// natural order, skipping
+// ignored directories as findProjectsArgs describes. Bare
+// repositories are included, as are linked worktrees unless the
+// discovery.worktrees setting is false.
+func findProjects(ctx context.Context, r execx.Runner) ([]string, error) {
+	var dirs []projectDir
+	for _, dir := range projectDirs() {
+		if strings.HasPrefix(dir.Path, "~/") {
+			dirs = append(dirs, dir)
+		}
+	}
+
+	q := &locateQuery{}
+	found := make([][]string, len(dirs))
+	errs := make([]error, len(dirs))
+	forEachParallel(len(dirs), func(i int) {
+		found[i], errs[i] = findProjectsIn(ctx, r, q, filepath.Join(xdg.Home, dirs[i].Path[2:]), dirs[i])
+	})
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
 	var result []string
+	for i := range dirs {
+		if errs[i] != nil {
+			return nil, errs[i]
+		}
+		result = append(result, found[i]...)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return collateLess(result[i], result[j])
+	})
 
-	projectDirs := viper.GetStringSlice("projects")
-	for _, dir := range projectDirs {
-		if strings.HasPrefix(dir, "~/") {
-			target := filepath.Join(homeDir, dir[2:])
-			log.Printf("\nScanning directory: %v (%v)", target, dir)
-			cmd := exec.Command("find", "-L", target, "-iname", ".git", "-type", "d", "-prune")
-			stdout, err := cmd.Output()
-			if err != nil {
-				if exiterr, ok := err.(*exec.ExitError); ok {
-					if exiterr.ExitCode() == 1 {
-						log.Printf("Exit status == 1: Ignoring")
-					} else {
-						log.Fatalf("error finding projects in %v: %v", target, err)
+	return result, nil
+}
+
+// projectDir is an entry of the projects setting: a directory to search
+// for projects and, optionally, globs limiting the search to the
+// subdirectories of it whose names match.
+type projectDir struct {
+	Path    string
+	Include []string
+}
+
+// projectDirs returns the entries of the projects setting, each either a
+// path or a mapping with a path and include globs:
+//
+//	projects:
+//	  - ~/projects
+//	  - path: ~/shared-checkouts
+//	    include: ["alice-*", "platform-*"]
+func projectDirs() []projectDir {
+	entries, ok := viper.Get("projects").([]any)
+	if !ok {
+		var dirs []projectDir
+		for _, path := range viper.GetStringSlice("projects") {
+			dirs = append(dirs, projectDir{Path: path})
+		}
+		return dirs
+	}
+
+	var dirs []projectDir
+	for _, entry := range entries {
+		switch e := entry.(type) {
+		case string:
+			dirs = append(dirs, projectDir{Path: e})
+		case map[string]any:
+			var dir projectDir
+			dir.Path, _ = e["path"].(string)
+			switch include := e["include"].(type) {
+			case string:
+				dir.Include = []string{include}
+			case []any:
+				for _, pattern := range include {
+					if p, ok := pattern.(string); ok {
+						dir.Include = append(dir.Include, p)
 					}
 				}
 			}
+			dirs = append(dirs, dir)
+		default:
+			log.Warnf("ignoring projects entry %v: expected a path or a path with include globs", entry)
+		}
+	}
+	return dirs
+}
 
-			for _, dir := range strings.Split(string(stdout), "\n") {
-				if len(dir) > 0 {
-					p := dir[:len(dir)-4]  // remove ".git" at the end
-					// log.Printf("%v\n", p)
-					result = append(result, strings.Replace(p, homeDir, "~", 1))
-				}
+// findProjectsIn returns the git repositories under target, the configured
+// project directory dir. It asks locate, through q, when its database is
+// fresh and knows of repositories there, and searches with find otherwise.
+// If dir has include globs, only the subdirectories of target they match
+// are searched.
+func findProjectsIn(ctx context.Context, r execx.Runner, q *locateQuery, target string, dir projectDir) ([]string, error) {
+	log.Printf("\nScanning directory: %v (%v)", target, dir.Path)
+	warnSelfPaths(target)
+	start := time.Now()
+
+	roots := []string{target}
+	if len(dir.Include) > 0 {
+		if roots = includedDirs(target, dir.Include); len(roots) == 0 {
+			return nil, nil
+		}
+	}
+
+	if !NoLocate {
+		if hits, ok := locateProjectHits(ctx, q, target, roots); ok {
+			result := projectsFromHits(hits)
+			log.Printf("Found %d projects in %v with locate in %v", len(result), target, time.Since(start))
+			return result, nil
+		}
+	}
+
+	stdout, _, err := r.Run(ctx, "", "find", findProjectsArgs(target, roots)...)
+	if err != nil {
+		if exiterr, ok := err.(*exec.ExitError); ok {
+			if exiterr.ExitCode() == 1 {
+				log.Printf("Exit status == 1: Ignoring")
+			} else {
+				return nil, fmt.Errorf("error finding projects in %v: %w", target, err)
 			}
 		}
 	}
 
-	fmt.Printf("%v", result)
-	// return fmt.Errorf("Heh")
-	return nil
+	var hits []string
+	for _, hit := range strings.Split(string(stdout), "\x00") {
+		if len(hit) > 0 {
+			hits = append(hits, hit)
+		}
+	}
+	result := projectsFromHits(hits)
+	log.Printf("Found %d projects in %v with find in %v", len(result), target, time.Since(start))
+	return result, nil
+}
+
+// projectsFromHits returns the repositories that the .git and HEAD paths
+// found by a search stand for.
+func projectsFromHits(hits []string) []string {
+	worktrees := viper.GetBool("discovery.worktrees")
+	var result []string
+	for _, hit := range hits {
+		p := filepath.Dir(hit) // remove "/.git" or "/HEAD" at the end
+		if isSelfPath(p) {
+			continue
+		}
+		if filepath.Base(hit) == "HEAD" && !isBareGitRepo(p) {
+			continue
+		}
+		if !worktrees && isGitWorktree(p) {
+			continue
+		}
+		result = append(result, p)
+	}
+	return result
+}
+
+// locateMaxAge is how old the locate database may be for discovery to
+// trust it.
+const locateMaxAge = 24 * time.Hour
+
+// locateQuery looks repositories up in the locate database once, on first
+// use, so that the project directories searched in parallel share one run
+// of locate.
+type locateQuery struct {
+	once  sync.Once
+	paths []string
+	age   time.Duration
+	err   error
+}
+
+// find returns the .git and HEAD paths in the locate database, sorted, and
+// the age of the database.
+func (q *locateQuery) find(ctx context.Context) ([]string, time.Duration, error) {
+	q.once.Do(func() {
+		q.age, q.err = locateFinder.DatabaseAge()
+		if q.err == nil && q.age > locateMaxAge {
+			q.err = fmt.Errorf("the database is %v old", q.age.Round(time.Minute))
+		}
+		if q.err == nil {
+			q.paths, q.err = locateFinder.Find(ctx, ".git", "HEAD")
+			sort.Strings(q.paths)
+		}
+	})
+	return q.paths, q.age, q.err
+}
+
+// pathsUnder returns the paths of sorted that are below dir.
+func pathsUnder(sorted []string, dir string) []string {
+	prefix := strings.TrimSuffix(dir, string(filepath.Separator)) + string(filepath.Separator)
+	i := sort.SearchStrings(sorted, prefix)
+	j := i
+	for j < len(sorted) && strings.HasPrefix(sorted[j], prefix) {
+		j++
+	}
+	return sorted[i:j]
+}
+
+// locateProjectHits returns the .git and HEAD paths under roots, which are
+// target or subdirectories of it, known to the locate database, filtered
+// as find would filter them: nothing inside a .git directory or an ignored
+// directory, nothing deeper than discovery.max_depth below target, and
+// nothing that no longer exists. It returns false if locate cannot be used
+// or knows of nothing there.
+//
+// It also returns false if the database may be missing repositories,
+// which is when a directory holding known ones, or one between them and
+// target, has changed since it was built, as creating a clone or worktree
+// there does, or holds a symlink to a directory, which find follows but
+// the database does not. Repositories created since then elsewhere, such
+// as in a new directory or inside another repository, and those behind
+// symlinks elsewhere, are missed until updatedb runs again.
+func locateProjectHits(ctx context.Context, q *locateQuery, target string, roots []string) ([]string, bool) {
+	all, age, err := q.find(ctx)
+	if err != nil {
+		log.Debugf("not using locate: %v", err)
+		return nil, false
+	}
+
+	// The database has paths with symlinks resolved.
+	real, err := filepath.EvalSymlinks(target)
+	if err != nil {
+		return nil, false
+	}
+	found := pathsUnder(all, real)
+
+	ignores := append(defaultIgnores, viper.GetStringSlice("discovery.ignore")...)
+	depth := viper.GetInt("discovery.max_depth")
+
+	var hits []string
+	for _, f := range found {
+		rel, err := filepath.Rel(real, f)
+		if err != nil {
+			continue
+		}
+		parts := strings.Split(rel, string(filepath.Separator))
+		if depth > 0 && len(parts) > depth+1 || ignoredPath(parts[:len(parts)-1], ignores) {
+			continue
+		}
+		if roots[0] != target && !slices.Contains(roots, filepath.Join(target, parts[0])) {
+			continue
+		}
+		if _, err := os.Lstat(f); err != nil {
+			continue
+		}
+		hits = append(hits, filepath.Join(target, rel))
+	}
+
+	dirs := hitDirs(target, hits)
+	if dir := changedSince(time.Now().Add(-age), dirs); dir != "" {
+		log.Debugf("not using locate: %v changed since the database was built", dir)
+		return nil, false
+	}
+	if link := linkedDir(dirs); link != "" {
+		log.Debugf("not using locate: %v is a symlink to a directory", link)
+		return nil, false
+	}
+	return hits, len(hits) > 0
+}
+
+// hitDirs returns target, the directories holding the repositories that
+// hits stand for and those between them and target, each once.
+func hitDirs(target string, hits []string) []string {
+	dirs := []string{target}
+	seen := map[string]bool{target: true}
+	for _, hit := range hits {
+		dir := filepath.Dir(filepath.Dir(hit))
+		for !seen[dir] && pathWithin(dir, target) {
+			seen[dir] = true
+			dirs = append(dirs, dir)
+			dir = filepath.Dir(dir)
+		}
+	}
+	return dirs
+}
+
+// changedSince returns the first of dirs that changed after t, or an empty
+// string if none did.
+func changedSince(t time.Time, dirs []string) string {
+	for _, dir := range dirs {
+		if info, err := os.Stat(dir); err == nil && info.ModTime().After(t) {
+			return dir
+		}
+	}
+	return ""
+}
+
+// linkedDir returns the first symlink to a directory found in dirs, or an
+// empty string if there is none.
+func linkedDir(dirs []string) string {
+	for _, dir := range dirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			if entry.Type()&fs.ModeSymlink == 0 {
+				continue
+			}
+			path := filepath.Join(dir, entry.Name())
+			if info, err := os.Stat(path); err == nil && info.IsDir() {
+				return path
+			}
+		}
+	}
+	return ""
+}
+
+// ignoredPath reports whether any of the directories dirs is a .git
+// directory or matches one of the ignore patterns.
+func ignoredPath(dirs []string, ignores []string) bool {
+	for _, dir := range dirs {
+		if strings.EqualFold(dir, ".git") {
+			return true
+		}
+		for _, pattern := range ignores {
+			if ok, _ := filepath.Match(pattern, dir); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// locateFinder looks repositories up in the locate database.
+var locateFinder locate.Finder = locate.Locate{Runner: runner}
+
+// defaultIgnores are the directories never searched for projects, as they
+// hold dependencies and caches rather than work of one's own.
+var defaultIgnores = []string{"node_modules", ".cache", ".terraform", "vendor", ".venv"}
+
+// includedDirs returns the subdirectories of target whose names match any
+// of the include globs and are not ignored, warning of globs that match
+// none of them.
+func includedDirs(target string, include []string) []string {
+	entries, err := os.ReadDir(target)
+	if err != nil {
+		log.Warnf("error reading %v: %v", target, err)
+		return nil
+	}
+	ignores := append(defaultIgnores, viper.GetStringSlice("discovery.ignore")...)
+
+	used := map[string]bool{}
+	var dirs []string
+	for _, entry := range entries {
+		path := filepath.Join(target, entry.Name())
+		if info, err := os.Stat(path); err != nil || !info.IsDir() || ignoredPath([]string{entry.Name()}, ignores) {
+			continue
+		}
+		matched := false
+		for _, pattern := range include {
+			if ok, _ := filepath.Match(pattern, entry.Name()); ok {
+				used[pattern], matched = true, true
+			}
+		}
+		if matched {
+			dirs = append(dirs, path)
+		}
+	}
+
+	for _, pattern := range include {
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			log.Warnf("invalid include pattern %q for %v: %v", pattern, target, err)
+		} else if !used[pattern] {
+			log.Warnf("include pattern %q matches no directory in %v", pattern, target)
+		}
+	}
+	return dirs
+}
+
+// findProjectsArgs returns the find arguments that print, each terminated
+// by a NUL byte as paths may hold newlines, the .git directories (without
+// descending into them), the .git files of linked worktrees and
+// submodules, and the HEAD files, which may be the top of bare
+// repositories, under roots, which are target or subdirectories of
+// it. Directories matching defaultIgnores or the discovery.ignore globs
+// are skipped entirely, and with discovery.max_depth set, repositories are
+// looked for at most that many levels below target.
+func findProjectsArgs(target string, roots []string) []string {
+	args := append([]string{"-L"}, roots...)
+	if depth := viper.GetInt("discovery.max_depth"); depth > 0 {
+		maxDepth := depth + 1
+		if roots[0] != target {
+			maxDepth--
+		}
+		args = append(args, "-maxdepth", strconv.Itoa(maxDepth))
+	}
+
+	args = append(args, "(", "-type", "d", "(")
+	for i, pattern := range append(defaultIgnores, viper.GetStringSlice("discovery.ignore")...) {
+		if i > 0 {
+			args = append(args, "-o")
+		}
+		args = append(args, "-name", pattern)
+	}
+	args = append(args, ")", "-prune", ")", "-o")
+
+	return append(args,
+		"(", "-iname", ".git", "-type", "d", "-prune", "-print0", ")", "-o",
+		"(", "-iname", ".git", "-type", "f", "-print0", ")", "-o",
+		"(", "-name", "HEAD", "-type", "f", "-print0", ")")
+}
+
+// tildePath abbreviates the home directory at the start of path to "~".
+func tildePath(path string) string {
+	if pathWithin(path, xdg.Home) {
+		return "~" + strings.TrimPrefix(path, xdg.Home)
+	}
+	return path
+}
+
+// expandPath expands a leading "~" in path to the home directory and cleans
+// the result.
+func expandPath(path string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		path = xdg.Home + path[1:]
+	}
+	return filepath.Clean(path)
+}
+
+// enclosingProject returns the innermost of projects that contains path,
+// or an empty string if none does.
+func enclosingProject(path string, projects []string) string {
+	var root string
+	for _, p := range projects {
+		if len(p) > len(root) && pathWithin(path, p) {
+			root = p
+		}
+	}
+	return root
+}
+
+// projectNames returns the names a project goes by: its directory name and
+// the names declared in its package manifests.
+func projectNames(project string) []string {
+	names := []string{filepath.Base(project)}
+	for _, id := range manifest.Identifiers(project) {
+		names = append(names, id.Value)
+	}
+	return names
+}
+
+// resolveProject picks the project named by query, which may be a path or
+// a full, leading or partial project name, or prefixes of the words of the
+// name such as "pdp" for platform-data-pipeline, matched case-insensitively
+// in that order of preference. Names declared in package manifests, such
+// as the module path in go.mod, count as project names too.
+func resolveProject(query string, projects []string) (string, error) {
+	if strings.HasPrefix(query, "~/") || filepath.IsAbs(query) {
+		path := expandPath(query)
+		for _, p := range projects {
+			if p == path {
+				return p, nil
+			}
+		}
+		return "", fmt.Errorf("%v is not a known project", query)
+	}
+
+	q := strings.ToLower(query)
+	matchers := []func(name string) bool{
+		func(name string) bool { return strings.ToLower(name) == q },
+		func(name string) bool { return strings.HasPrefix(strings.ToLower(name), q) },
+		func(name string) bool { return strings.Contains(strings.ToLower(name), q) },
+		// Word matching needs the original case to see camelCase humps.
+		func(name string) bool { return fuzzy.Explain(query, name).Words > 0 },
+	}
+
+	names := make([][]string, len(projects))
+	for i, p := range projects {
+		names[i] = projectNames(p)
+	}
+
+	for _, match := range matchers {
+		var found []string
+		for i, p := range projects {
+			if slices.ContainsFunc(names[i], match) {
+				found = append(found, p)
+			}
+		}
+
+		switch len(found) {
+		case 0:
+			continue
+		case 1:
+			return found[0], nil
+		default:
+			for i := range found {
+				found[i] = tildePath(found[i])
+			}
+			return "", fmt.Errorf("%q matches several projects: %v", query, strings.Join(found, ", "))
+		}
+	}
+
+	return "", fmt.Errorf("no project matches %q", query)
 }
@@ -0,0 +1,566 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/adrg/xdg"
+	"github.com/shalomb/gum/internal/execx"
+	"github.com/spf13/viper"
+)
+
+// exitError returns the error of a process that exited with code.
+func exitError(t *testing.T, code int) error {
+	t.Helper()
+	err := exec.Command("sh", "-c", fmt.Sprintf("exit %d", code)).Run()
+	if _, ok := err.(*exec.ExitError); !ok {
+		t.Fatalf("cannot make an exit status %v: %v", code, err)
+	}
+	return err
+}
+
+// withHome runs the test with an empty temporary home directory, symlinks
+// resolved, and returns it.
+func withHome(t testing.TB) string {
+	t.Helper()
+	home, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Registered first to run last, once the environment is restored.
+	t.Cleanup(xdg.Reload)
+	t.Setenv("HOME", home)
+	for _, v := range []string{"XDG_CONFIG_HOME", "XDG_CACHE_HOME", "XDG_DATA_HOME"} {
+		t.Setenv(v, "")
+	}
+	xdg.Reload()
+	return home
+}
+
+// withProjects runs the test with the projects setting set to dirs.
+func withProjects(t testing.TB, dirs ...any) {
+	t.Helper()
+	viper.Set("projects", dirs)
+	t.Cleanup(func() { viper.Set("projects", nil) })
+}
+
+// withoutLocate runs the test with the locate fast path turned off.
+func withoutLocate(t testing.TB) {
+	t.Helper()
+	NoLocate = true
+	t.Cleanup(func() { NoLocate = false })
+}
+
+func TestFindProjectsInKeepsPartialOutput(t *testing.T) {
+	withoutLocate(t)
+	target := t.TempDir()
+
+	// find exits 1 when some directories cannot be read, but what it
+	// printed is still good.
+	r := &execx.Fake{Responses: []execx.FakeResponse{{
+		Argv:   []string{"find", "-L", target},
+		Stdout: target + "/a/.git\x00" + target + "/b/c/.git\x00" + target + "/d/HEAD\x00",
+		Stderr: "find: '" + target + "/private': Permission denied\n",
+		Err:    exitError(t, 1),
+	}}}
+
+	got, err := findProjectsIn(context.Background(), r, &locateQuery{}, target, projectDir{Path: "~/test"})
+	if err != nil {
+		t.Fatalf("findProjectsIn failed: %v", err)
+	}
+	// d is not a bare repository, so its HEAD does not count.
+	want := []string{target + "/a", target + "/b/c"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("findProjectsIn = %q, want %q", got, want)
+	}
+}
+
+func TestFindProjectsInFails(t *testing.T) {
+	withoutLocate(t)
+	target := t.TempDir()
+	r := &execx.Fake{Responses: []execx.FakeResponse{{
+		Argv: []string{"find"},
+		Err:  exitError(t, 2),
+	}}}
+
+	if got, err := findProjectsIn(context.Background(), r, &locateQuery{}, target, projectDir{Path: "~/test"}); err == nil {
+		t.Errorf("findProjectsIn = %q; want an error for find exiting 2", got)
+	}
+}
+
+// fakeFinder is a locate.Finder with a canned database.
+type fakeFinder struct {
+	age   time.Duration
+	paths []string
+}
+
+func (f fakeFinder) Find(ctx context.Context, names ...string) ([]string, error) {
+	return f.paths, nil
+}
+
+func (f fakeFinder) DatabaseAge() (time.Duration, error) {
+	return f.age, nil
+}
+
+func TestLocateProjectHitsFallsBackOnChanges(t *testing.T) {
+	target, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	known := filepath.Join(target, "org", "known", ".git")
+	if err := os.MkdirAll(known, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	saved := locateFinder
+	t.Cleanup(func() { locateFinder = saved })
+	locateFinder = fakeFinder{age: time.Hour, paths: []string{known}}
+
+	// Everything was there before the database was built.
+	built := time.Now().Add(-2 * time.Hour)
+	for _, dir := range []string{target, filepath.Join(target, "org")} {
+		if err := os.Chtimes(dir, built, built); err != nil {
+			t.Fatal(err)
+		}
+	}
+	hits, ok := locateProjectHits(context.Background(), &locateQuery{}, target, []string{target})
+	if !ok || !reflect.DeepEqual(hits, []string{known}) {
+		t.Errorf("locateProjectHits = %q, %v; want the known repository", hits, ok)
+	}
+
+	// A clone next to the known repository is not in the database yet.
+	if err := os.MkdirAll(filepath.Join(target, "org", "new", ".git"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if hits, ok := locateProjectHits(context.Background(), &locateQuery{}, target, []string{target}); ok {
+		t.Errorf("locateProjectHits = %q after a new clone; want to fall back to find", hits)
+	}
+}
+
+func TestLocateProjectHitsIgnoresStaleDatabase(t *testing.T) {
+	saved := locateFinder
+	t.Cleanup(func() { locateFinder = saved })
+	locateFinder = fakeFinder{age: 2 * locateMaxAge, paths: []string{"/p/a/.git"}}
+
+	if hits, ok := locateProjectHits(context.Background(), &locateQuery{}, "/p", []string{"/p"}); ok {
+		t.Errorf("locateProjectHits = %q with a stale database; want to fall back to find", hits)
+	}
+}
+
+// recordingRunner runs programs for real and records their arguments.
+type recordingRunner struct {
+	execx.Exec
+	calls [][]string
+}
+
+func (r *recordingRunner) Run(ctx context.Context, dir, name string, args ...string) ([]byte, []byte, error) {
+	r.calls = append(r.calls, append([]string{name}, args...))
+	return execx.Exec{}.Run(ctx, dir, name, args...)
+}
+
+func TestIncludedDirs(t *testing.T) {
+	target, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	repos := map[string]bool{
+		"alice-web/app":    true,
+		"alice-web/lib":    true,
+		"platform-infra/x": true,
+		"bob-tools/y":      false,
+		"node_modules/z":   false,
+		"alice-x/.cache/q": false,
+	}
+	for repo := range repos {
+		if err := os.MkdirAll(filepath.Join(target, repo, ".git"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeFile(t, filepath.Join(target, "alice-notes"), "not a directory")
+	viper.Set("discovery.ignore", []string{"alice-x"})
+	t.Cleanup(func() { viper.Set("discovery.ignore", nil) })
+	dir := projectDir{Path: "~/shared", Include: []string{"alice-*", "platform-*", "carol-*"}}
+	logged := captureLog(t)
+
+	got := includedDirs(target, dir.Include)
+	want := []string{filepath.Join(target, "alice-web"), filepath.Join(target, "platform-infra")}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("includedDirs = %q, want %q", got, want)
+	}
+	// logrus quotes the message, escaping the quotes around the pattern.
+	if w := `include pattern \"carol-*\" matches no directory in ` + target; !strings.Contains(logged.String(), w) {
+		t.Errorf("no warning %q in:\n%s", w, logged)
+	}
+	for _, pattern := range []string{"alice-*", "platform-*"} {
+		if strings.Contains(logged.String(), pattern+`\" matches no directory`) {
+			t.Errorf("warned that %q matches nothing", pattern)
+		}
+	}
+
+	var wantProjects []string
+	for repo, included := range repos {
+		if included {
+			wantProjects = append(wantProjects, filepath.Join(target, repo))
+		}
+	}
+	sort.Strings(wantProjects)
+
+	// find is only pointed at the matching subtrees.
+	withoutLocate(t)
+	r := &recordingRunner{}
+	logged.Reset()
+	projects, err := findProjectsIn(context.Background(), r, &locateQuery{}, target, dir)
+	if err != nil {
+		t.Fatalf("findProjectsIn failed: %v", err)
+	}
+	sort.Strings(projects)
+	if !reflect.DeepEqual(projects, wantProjects) {
+		t.Errorf("findProjectsIn = %q, want %q", projects, wantProjects)
+	}
+	if len(r.calls) != 1 || !reflect.DeepEqual(r.calls[0][:4], append([]string{"find", "-L"}, want...)) {
+		t.Errorf("ran %q, want a find of %q only", r.calls, want)
+	}
+	if w := fmt.Sprintf("Found %d projects in %v", len(wantProjects), target); !strings.Contains(logged.String(), w) {
+		t.Errorf("no %q in:\n%s", w, logged)
+	}
+
+	// locate hits outside them are dropped.
+	NoLocate = false
+	saved := locateFinder
+	t.Cleanup(func() { locateFinder = saved })
+	var hits []string
+	for repo := range repos {
+		hits = append(hits, filepath.Join(target, repo, ".git"))
+	}
+	locateFinder = fakeFinder{age: time.Hour, paths: hits}
+	built := time.Now().Add(-2 * time.Hour)
+	filepath.WalkDir(target, func(path string, d os.DirEntry, err error) error {
+		if err == nil && d.IsDir() {
+			os.Chtimes(path, built, built)
+		}
+		return nil
+	})
+	r = &recordingRunner{}
+	projects, err = findProjectsIn(context.Background(), r, &locateQuery{}, target, dir)
+	if err != nil {
+		t.Fatalf("findProjectsIn with locate failed: %v", err)
+	}
+	sort.Strings(projects)
+	if !reflect.DeepEqual(projects, wantProjects) || len(r.calls) > 0 {
+		t.Errorf("findProjectsIn with locate = %q after running %q, want %q without find", projects, r.calls, wantProjects)
+	}
+
+	// Nothing is searched when no pattern matches.
+	r = &recordingRunner{}
+	if projects, _ := findProjectsIn(context.Background(), r, &locateQuery{}, target, projectDir{Path: "~/shared", Include: []string{"carol-*"}}); len(projects) > 0 || len(r.calls) > 0 {
+		t.Errorf("findProjectsIn = %q after running %q, want nothing searched", projects, r.calls)
+	}
+}
+
+// visitRunner runs find as asked, but also records in a file every path
+// it visits that the expression does not prune or print.
+type visitRunner struct {
+	execx.Exec
+	visited string
+}
+
+func (r visitRunner) Run(ctx context.Context, dir, name string, args ...string) ([]byte, []byte, error) {
+	args = append(args, "-o", "-fprint", r.visited)
+	return execx.Exec{}.Run(ctx, dir, name, args...)
+}
+
+// walkFinder is a locate.Finder whose database is a walk of the file
+// system under root, which like updatedb does not follow symlinks.
+type walkFinder struct {
+	root string
+}
+
+func (f walkFinder) Find(ctx context.Context, names ...string) ([]string, error) {
+	var paths []string
+	err := filepath.WalkDir(f.root, func(path string, d os.DirEntry, err error) error {
+		if err == nil && path != f.root {
+			for _, name := range names {
+				if d.Name() == name {
+					paths = append(paths, path)
+				}
+			}
+		}
+		return err
+	})
+	return paths, err
+}
+
+func (walkFinder) DatabaseAge() (time.Duration, error) {
+	return time.Hour, nil
+}
+
+// deepTree makes repositories at several depths under target, some of
+// them inside ignored directories, and returns the relative paths of
+// those at most maxDepth levels deep outside ignored directories.
+func deepTree(t *testing.T, target string, maxDepth int) []string {
+	t.Helper()
+	var want []string
+	for _, repo := range []string{
+		"a",
+		"org/b",
+		"org/team/c",
+		"org/team/sub/d",
+		"org/team/sub/deeper/e",
+		"node_modules/pkg",
+		"org/vendor/dep",
+		"org/team/build-out/f",
+		"org/b/.venv/lib/g",
+	} {
+		if err := os.MkdirAll(filepath.Join(target, repo, ".git", "refs", "heads"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		writeFile(t, filepath.Join(target, repo, ".git", "HEAD"), "ref: refs/heads/main\n")
+		parts := strings.Split(repo, "/")
+		if len(parts) <= maxDepth && !ignoredPath(parts, append(defaultIgnores, "build-*")) {
+			want = append(want, repo)
+		}
+	}
+	sort.Strings(want)
+	return want
+}
+
+func TestFindProjectsSkipsIgnoredPaths(t *testing.T) {
+	withoutLocate(t)
+	viper.Set("discovery.ignore", []string{"build-*"})
+	t.Cleanup(func() {
+		viper.Set("discovery.ignore", nil)
+		viper.Set("discovery.max_depth", nil)
+	})
+
+	for _, depth := range []int{0, 3} {
+		target, err := filepath.EvalSymlinks(t.TempDir())
+		if err != nil {
+			t.Fatal(err)
+		}
+		viper.Set("discovery.max_depth", depth)
+		maxDepth := depth
+		if maxDepth == 0 {
+			maxDepth = 100
+		}
+		want := deepTree(t, target, maxDepth)
+
+		visited := filepath.Join(t.TempDir(), "visited")
+		projects, err := findProjectsIn(context.Background(), visitRunner{visited: visited}, &locateQuery{}, target, projectDir{Path: "~/deep"})
+		if err != nil {
+			t.Fatalf("findProjectsIn failed: %v", err)
+		}
+		var got []string
+		for _, p := range projects {
+			rel, _ := filepath.Rel(target, p)
+			got = append(got, rel)
+		}
+		sort.Strings(got)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("with max_depth %v, findProjectsIn = %q, want %q", depth, got, want)
+		}
+
+		data, err := os.ReadFile(visited)
+		if err != nil {
+			t.Fatal(err)
+		}
+		paths := strings.Split(strings.TrimSpace(string(data)), "\n")
+		if !slices.Contains(paths, filepath.Join(target, "org", "team")) {
+			t.Errorf("find did not visit org/team; visited %q", paths)
+		}
+		for _, path := range paths {
+			rel, _ := filepath.Rel(target, path)
+			parts := strings.Split(rel, "/")
+			if ignoredPath(parts[:len(parts)-1], append(defaultIgnores, "build-*")) {
+				t.Errorf("find visited %v, inside an ignored or .git directory", rel)
+			}
+			if depth > 0 && len(parts) > depth+1 {
+				t.Errorf("find visited %v, deeper than max_depth %v", rel, depth)
+			}
+		}
+	}
+}
+
+func TestFindProjectsArgs(t *testing.T) {
+	viper.Set("discovery.ignore", []string{"build-*"})
+	t.Cleanup(func() {
+		viper.Set("discovery.ignore", nil)
+		viper.Set("discovery.max_depth", nil)
+	})
+	prune := []string{"(", "-type", "d", "(",
+		"-name", "node_modules", "-o", "-name", ".cache", "-o", "-name", ".terraform", "-o",
+		"-name", "vendor", "-o", "-name", ".venv", "-o", "-name", "build-*", ")", "-prune", ")", "-o",
+		"(", "-iname", ".git", "-type", "d", "-prune", "-print0", ")", "-o",
+		"(", "-iname", ".git", "-type", "f", "-print0", ")", "-o",
+		"(", "-name", "HEAD", "-type", "f", "-print0", ")"}
+
+	tests := []struct {
+		depth int
+		roots []string
+		want  []string
+	}{
+		{0, []string{"/p"}, []string{"-L", "/p"}},
+		{2, []string{"/p"}, []string{"-L", "/p", "-maxdepth", "3"}},
+		{2, []string{"/p/a", "/p/b"}, []string{"-L", "/p/a", "/p/b", "-maxdepth", "2"}},
+	}
+	for _, tt := range tests {
+		viper.Set("discovery.max_depth", tt.depth)
+		want := append(tt.want, prune...)
+		if got := findProjectsArgs("/p", tt.roots); !reflect.DeepEqual(got, want) {
+			t.Errorf("findProjectsArgs(%q) with max_depth %v =\n%q\nwant\n%q", tt.roots, tt.depth, got, want)
+		}
+	}
+}
+
+func TestLocateAgreesWithFind(t *testing.T) {
+	withoutLocate(t)
+	viper.Set("discovery.ignore", []string{"build-*"})
+	t.Cleanup(func() {
+		viper.Set("discovery.ignore", nil)
+		viper.Set("discovery.max_depth", nil)
+	})
+	saved := locateFinder
+	t.Cleanup(func() { locateFinder = saved })
+
+	for _, depth := range []int{0, 3} {
+		viper.Set("discovery.max_depth", depth)
+		target, err := filepath.EvalSymlinks(t.TempDir())
+		if err != nil {
+			t.Fatal(err)
+		}
+		locateFinder = walkFinder{target}
+		deepTree(t, target, 0)
+		if err := os.MkdirAll(filepath.Join(target, "bare.git", "refs"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		writeFile(t, filepath.Join(target, "bare.git", "HEAD"), "ref: refs/heads/main\n")
+		built := time.Now().Add(-2 * time.Hour)
+		filepath.WalkDir(target, func(path string, d os.DirEntry, err error) error {
+			if err == nil && d.IsDir() {
+				os.Chtimes(path, built, built)
+			}
+			return nil
+		})
+
+		for _, dir := range []projectDir{{Path: "~/deep"}, {Path: "~/deep", Include: []string{"org", "bare*"}}} {
+			NoLocate = true
+			r := &recordingRunner{}
+			byFind, err := findProjectsIn(context.Background(), r, &locateQuery{}, target, dir)
+			if err != nil || len(r.calls) != 1 {
+				t.Fatalf("findProjectsIn with find = %v after running %q", err, r.calls)
+			}
+			NoLocate = false
+			r = &recordingRunner{}
+			byLocate, err := findProjectsIn(context.Background(), r, &locateQuery{}, target, dir)
+			if err != nil || len(r.calls) != 0 {
+				t.Fatalf("findProjectsIn with locate = %v after running %q", err, r.calls)
+			}
+			sort.Strings(byFind)
+			sort.Strings(byLocate)
+			if !reflect.DeepEqual(byLocate, byFind) {
+				t.Errorf("with max_depth %v and include %q, locate found\n%q\nbut find found\n%q", depth, dir.Include, byLocate, byFind)
+			}
+		}
+	}
+}
+
+// countingFinder is a locate.Finder that counts the queries it answers.
+type countingFinder struct {
+	walkFinder
+	queries *atomic.Int32
+}
+
+func (f countingFinder) Find(ctx context.Context, names ...string) ([]string, error) {
+	f.queries.Add(1)
+	return f.walkFinder.Find(ctx, names...)
+}
+
+func TestLocateAgreesWithFindOnSymlinks(t *testing.T) {
+	home := withHome(t)
+	withoutLocate(t)
+	withProjects(t, "~/src", "~/mirror")
+	saved := locateFinder
+	t.Cleanup(func() { locateFinder = saved })
+	queries := &atomic.Int32{}
+	locateFinder = countingFinder{walkFinder{home}, queries}
+
+	// ~/src/org/linked is a symlink to a repository elsewhere, which find
+	// follows but the locate database does not, and ~/mirror is itself a
+	// symlink to the directory holding its repositories.
+	for _, repo := range []string{"src/org/app", "elsewhere/linked-repo", "mirrored/x", "mirrored/y"} {
+		if err := os.MkdirAll(filepath.Join(home, repo, ".git"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink(filepath.Join(home, "elsewhere", "linked-repo"), filepath.Join(home, "src", "org", "linked")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(home, "mirrored"), filepath.Join(home, "mirror")); err != nil {
+		t.Fatal(err)
+	}
+	built := time.Now().Add(-2 * time.Hour)
+	filepath.WalkDir(home, func(path string, d os.DirEntry, err error) error {
+		if err == nil && d.IsDir() {
+			os.Chtimes(path, built, built)
+		}
+		return nil
+	})
+
+	r := &recordingRunner{}
+	byFind, err := findProjects(context.Background(), r)
+	if err != nil {
+		t.Fatalf("findProjects with find failed: %v", err)
+	}
+	want := []string{
+		filepath.Join(home, "mirror", "x"),
+		filepath.Join(home, "mirror", "y"),
+		filepath.Join(home, "src", "org", "app"),
+		filepath.Join(home, "src", "org", "linked"),
+	}
+	if !reflect.DeepEqual(byFind, want) {
+		t.Errorf("findProjects with find = %q, want %q", byFind, want)
+	}
+
+	NoLocate = false
+	r = &recordingRunner{}
+	byLocate, err := findProjects(context.Background(), r)
+	if err != nil {
+		t.Fatalf("findProjects with locate failed: %v", err)
+	}
+	if !reflect.DeepEqual(byLocate, byFind) {
+		t.Errorf("findProjects with locate = %q, but with find %q", byLocate, byFind)
+	}
+	// Only ~/src, which holds a symlink locate cannot see through, needs
+	// find, and locate ran once for both directories.
+	if len(r.calls) != 1 || r.calls[0][2] != filepath.Join(home, "src") {
+		t.Errorf("ran %q, want find of ~/src only", r.calls)
+	}
+	if n := queries.Load(); n != 1 {
+		t.Errorf("locate ran %v times, want once", n)
+	}
+}
+
+func TestPathsUnder(t *testing.T) {
+	sorted := []string{"/p", "/p-other/a", "/p/a", "/p/b/c", "/pa", "/q/a"}
+	tests := map[string][]string{
+		"/p":  {"/p/a", "/p/b/c"},
+		"/p/": {"/p/a", "/p/b/c"},
+		"/q":  {"/q/a"},
+		"/r":  {},
+		"/":   sorted,
+	}
+	for dir, want := range tests {
+		if got := pathsUnder(sorted, dir); !reflect.DeepEqual(got, want) {
+			t.Errorf("pathsUnder(%q) = %q, want %q", dir, got, want)
+		}
+	}
+}
@@ -5,23 +5,84 @@ Copyright © 2023 NAME HERE <EMAIL ADDRESS>
 package cmd
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
 
+	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 )
 
+// Build information, set at link time, e.g.
+//
+//	go build -ldflags "-X github.com/shalomb/gum/cmd.version=v0.1.0"
+//
+// Anything left empty is filled in from the build info embedded by the go
+// toolchain where possible.
+var (
+	version = ""
+	commit  = ""
+	date    = ""
+)
+
+// BuildInfo describes the running binary
+type BuildInfo struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	Date      string `json:"date"`
+	Dirty     bool   `json:"dirty"`
+	GoVersion string `json:"go_version"`
+	Platform  string `json:"platform"`
+}
+
 // versionCmd represents the version command
 var versionCmd = &cobra.Command{
 	Use:   "version",
-	Short: "A brief description of your command",
-	Long: `A longer description that spans multiple lines and likely contains examples
-and usage of using your command. For example:
-
-Cobra is a CLI library for Go that empowers applications.
-This application is a tool to generate the needed files
-to quickly create a Cobra application.`,
-	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Println("version called")
+	Short: "Print the version and build information",
+	Long: `Print the version of gum along with the commit and date it was built from,
+the go toolchain and platform. Please include this in bug reports.
+
+  gum version
+  gum version --format json --pretty
+
+--check also asks GitHub for the latest release and says whether this one
+is out of date. For a build that is not of a release, such as one from a
+modified tree or from a commit between releases, the answer is unknown.
+The latest release is cached for a day. Set version.check to false in the
+config to disable network checks entirely.`,
+	SilenceUsage: true,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, _ := cmd.Flags().GetString("format")
+		pretty, _ := cmd.Flags().GetBool("pretty")
+		info := getBuildInfo()
+
+		if err := checkVersionFormat(format); err != nil {
+			return err
+		}
+		if check, _ := cmd.Flags().GetBool("check"); check {
+			return doVersionCheck(cmd.Context(), info, format, pretty)
+		}
+
+		if format == "json" {
+			return writeJSON(os.Stdout, info, pretty)
+		}
+		fmt.Printf("gum %s (commit %s, built %s", info.Version, info.Commit, info.Date)
+		if info.Dirty {
+			fmt.Printf(", modified")
+		}
+		fmt.Printf(") %s %s\n", info.GoVersion, info.Platform)
+		return nil
 	},
 }
 
@@ -36,5 +97,236 @@ func init() {
 
 	// Cobra supports local flags which will only run when this command
 	// is called directly, e.g.:
-	// versionCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+	versionCmd.Flags().StringP("format", "f", "default", "Output format (default, json)")
+	versionCmd.Flags().Bool("pretty", false, "Indent JSON output")
+	versionCmd.Flags().Bool("check", false, "Check whether a newer release is available")
+
+	viper.SetDefault("version.check", true)
+}
+
+// latestReleaseURL is the GitHub API endpoint for gum's latest release.
+var latestReleaseURL = "https://api.github.com/repos/shalomb/gum/releases/latest"
+
+// releaseCheckTTL is how long the result of a release check is reused.
+const releaseCheckTTL = 24 * time.Hour
+
+// latestRelease is what gum knows about its latest release, as cached.
+type latestRelease struct {
+	Version   string    `json:"version"`
+	URL       string    `json:"url"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// versionCheck is the result of gum version --check. Status is
+// "up_to_date", "out_of_date" or "unknown", for builds that are not of a
+// release; UpToDate is false unless Status is "up_to_date".
+type versionCheck struct {
+	Current  string `json:"current"`
+	Latest   string `json:"latest"`
+	URL      string `json:"url"`
+	UpToDate bool   `json:"up_to_date"`
+	Status   string `json:"status"`
+}
+
+// checkVersionFormat validates the --format of gum version.
+func checkVersionFormat(format string) error {
+	switch format {
+	case "default", "json":
+		return nil
+	}
+	return fmt.Errorf("invalid --format %q: expected default or json", format)
+}
+
+// doVersionCheck reports whether info is the latest release, in format.
+func doVersionCheck(ctx context.Context, info BuildInfo, format string, pretty bool) error {
+	if !viper.GetBool("version.check") {
+		return fmt.Errorf("release checks are disabled by version.check in the config")
+	}
+
+	latest, err := getLatestRelease(ctx, time.Now())
+	if err != nil {
+		return err
+	}
+	result := versionCheck{
+		Current: info.Version,
+		Latest:  latest.Version,
+		URL:     latest.URL,
+	}
+	switch {
+	case !isReleaseBuild(info):
+		result.Status = "unknown"
+	case compareVersions(info.Version, latest.Version) >= 0:
+		result.Status, result.UpToDate = "up_to_date", true
+	default:
+		result.Status = "out_of_date"
+	}
+
+	if format == "json" {
+		return writeJSON(os.Stdout, result, pretty)
+	}
+	switch result.Status {
+	case "unknown":
+		fmt.Printf("gum %s is not a release build, so it may or may not be up to date (latest release %s)\n", result.Current, result.Latest)
+	case "up_to_date":
+		fmt.Printf("gum %s is up to date (latest release %s)\n", result.Current, result.Latest)
+	default:
+		fmt.Printf("gum %s is out of date: %s is available at %s\n", result.Current, result.Latest, result.URL)
+	}
+	return nil
+}
+
+// nonReleaseVersion matches the versions of builds between releases: git
+// describe output such as v1.2.3-4-gabcdef, go pseudo-versions such as
+// v0.0.0-20230501090000-abcdef123456, and versions marked -dirty or +dirty.
+var nonReleaseVersion = regexp.MustCompile(`-\d+-g[0-9a-f]+$|\d{14}-[0-9a-f]{12}$|[-+]dirty$`)
+
+// isReleaseBuild reports whether info is of a build of a release, whose
+// version can be compared with the latest release: a version like v1.2.3
+// built from an unmodified tree.
+func isReleaseBuild(info BuildInfo) bool {
+	if _, ok := versionParts(info.Version); !ok || info.Dirty {
+		return false
+	}
+	return !nonReleaseVersion.MatchString(info.Version)
+}
+
+// getLatestRelease returns the latest release of gum, from the cache if it
+// was checked less than releaseCheckTTL before now, or else from GitHub.
+func getLatestRelease(ctx context.Context, now time.Time) (latestRelease, error) {
+	cache := filepath.Join(viper.GetString("CacheDir"), "gum", "latest-release.json")
+
+	var cached latestRelease
+	if data, err := os.ReadFile(cache); err == nil && json.Unmarshal(data, &cached) == nil {
+		if now.Sub(cached.CheckedAt) < releaseCheckTTL && cached.Version != "" {
+			return cached, nil
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, latestReleaseURL, nil)
+	if err != nil {
+		return latestRelease{}, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return latestRelease{}, fmt.Errorf("error checking for releases: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return latestRelease{}, fmt.Errorf("error checking for releases: gum has no releases yet")
+	}
+	if resp.StatusCode != http.StatusOK {
+		return latestRelease{}, fmt.Errorf("error checking for releases: %v", resp.Status)
+	}
+
+	var release struct {
+		TagName string `json:"tag_name"`
+		HTMLURL string `json:"html_url"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(&release); err != nil {
+		return latestRelease{}, fmt.Errorf("error reading release: %w", err)
+	}
+
+	latest := latestRelease{Version: release.TagName, URL: release.HTMLURL, CheckedAt: now}
+	if data, err := json.Marshal(latest); err == nil {
+		if err := os.MkdirAll(filepath.Dir(cache), 0o755); err == nil {
+			err = writeFileAtomic(cache, data, 0o644)
+		}
+		if err != nil {
+			log.Debugf("error caching release check: %v", err)
+		}
+	}
+	return latest, nil
+}
+
+// compareVersions compares two versions like v1.2.3, returning -1, 0 or 1.
+// Numeric parts are compared as numbers and pre-release suffixes are
+// ignored. A version that is not of this form, such as "dev", is older
+// than any that is.
+func compareVersions(a, b string) int {
+	pa, oka := versionParts(a)
+	pb, okb := versionParts(b)
+	switch {
+	case !oka && !okb:
+		return 0
+	case !oka:
+		return -1
+	case !okb:
+		return 1
+	}
+	for i := 0; i < 3; i++ {
+		if pa[i] != pb[i] {
+			if pa[i] < pb[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// versionParts returns the major, minor and patch numbers of v.
+func versionParts(v string) ([3]int, bool) {
+	var parts [3]int
+	v = strings.TrimPrefix(v, "v")
+	v, _, _ = strings.Cut(v, "-")
+	v, _, _ = strings.Cut(v, "+")
+	fields := strings.Split(v, ".")
+	if len(fields) == 0 || len(fields) > 3 {
+		return parts, false
+	}
+	for i, f := range fields {
+		n, err := strconv.Atoi(f)
+		if err != nil {
+			return parts, false
+		}
+		parts[i] = n
+	}
+	return parts, true
+}
+
+// getBuildInfo merges the link time variables with the build info recorded
+// by the go toolchain, preferring the former.
+func getBuildInfo() BuildInfo {
+	info := BuildInfo{
+		Version:   version,
+		Commit:    commit,
+		Date:      date,
+		GoVersion: runtime.Version(),
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+	}
+
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		if info.Version == "" && bi.Main.Version != "(devel)" {
+			info.Version = bi.Main.Version
+		}
+		for _, s := range bi.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				if info.Commit == "" {
+					info.Commit = s.Value
+				}
+			case "vcs.time":
+				if info.Date == "" {
+					info.Date = s.Value
+				}
+			case "vcs.modified":
+				info.Dirty = s.Value == "true"
+			}
+		}
+	}
+
+	if info.Version == "" {
+		info.Version = "dev"
+	}
+	if info.Commit == "" {
+		info.Commit = "unknown"
+	}
+	if info.Date == "" {
+		info.Date = "unknown"
+	}
+	return info
 }
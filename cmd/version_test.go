@@ -0,0 +1,237 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+func TestVersionParts(t *testing.T) {
+	tests := []struct {
+		v    string
+		want [3]int
+		ok   bool
+	}{
+		{"v1.2.3", [3]int{1, 2, 3}, true},
+		{"1.2.3", [3]int{1, 2, 3}, true},
+		{"v1.2", [3]int{1, 2, 0}, true},
+		{"v2", [3]int{2, 0, 0}, true},
+		{"v1.2.3-rc.1", [3]int{1, 2, 3}, true},
+		{"v1.2.3+meta.5", [3]int{1, 2, 3}, true},
+		{"v1.2.3-rc.1+meta", [3]int{1, 2, 3}, true},
+		{"v0.0.0-20230501090000-abcdef123456", [3]int{0, 0, 0}, true},
+		{"v1.2.3.4", [3]int{}, false},
+		{"dev", [3]int{}, false},
+		{"", [3]int{}, false},
+		{"v1.x.3", [3]int{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := versionParts(tt.v)
+		if ok != tt.ok || ok && got != tt.want {
+			t.Errorf("versionParts(%q) = %v, %v, want %v, %v", tt.v, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"v1.2.3", "v1.2.3", 0},
+		{"v1.2.3", "1.2.3", 0},
+		{"v1.2.3", "v1.2.4", -1},
+		{"v1.10.0", "v1.9.0", 1},
+		{"v2.0.0", "v1.99.99", 1},
+		{"v1.2", "v1.2.0", 0},
+		// Pre-releases and build metadata are ignored.
+		{"v1.2.3-rc.1", "v1.2.3", 0},
+		{"v1.2.3+meta", "v1.2.3", 0},
+		{"v1.2.3-rc.1", "v1.2.2", 1},
+		// Versions gum cannot read are older than any it can.
+		{"dev", "v0.0.1", -1},
+		{"v0.0.1", "dev", 1},
+		{"dev", "dev", 0},
+		{"v1.2.3.4", "v0.1.0", -1},
+	}
+	for _, tt := range tests {
+		if got := compareVersions(tt.a, tt.b); got != tt.want {
+			t.Errorf("compareVersions(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+// releaseServer serves the latest release as GitHub would, answering with
+// status unless it is 200, and counts the requests in *requests.
+func releaseServer(t *testing.T, status int, requests *int) {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*requests++
+		if status != http.StatusOK {
+			http.Error(w, http.StatusText(status), status)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{
+			"tag_name": "v1.4.0",
+			"html_url": "https://github.com/shalomb/gum/releases/tag/v1.4.0",
+		})
+	}))
+
+	savedURL := latestReleaseURL
+	latestReleaseURL = server.URL
+	viper.Set("CacheDir", t.TempDir())
+	t.Cleanup(func() {
+		server.Close()
+		latestReleaseURL = savedURL
+		viper.Set("CacheDir", nil)
+	})
+}
+
+func TestGetLatestReleaseCaches(t *testing.T) {
+	var requests int
+	releaseServer(t, http.StatusOK, &requests)
+	now := time.Date(2023, 5, 1, 9, 0, 0, 0, time.UTC)
+
+	latest, err := getLatestRelease(context.Background(), now)
+	if err != nil {
+		t.Fatalf("getLatestRelease failed: %v", err)
+	}
+	want := latestRelease{Version: "v1.4.0", URL: "https://github.com/shalomb/gum/releases/tag/v1.4.0", CheckedAt: now}
+	if latest != want {
+		t.Errorf("getLatestRelease = %+v, want %+v", latest, want)
+	}
+
+	data, err := os.ReadFile(filepath.Join(viper.GetString("CacheDir"), "gum", "latest-release.json"))
+	if err != nil {
+		t.Fatalf("the release was not cached: %v", err)
+	}
+	var cached latestRelease
+	if err := json.Unmarshal(data, &cached); err != nil || !cached.CheckedAt.Equal(now) || cached.Version != want.Version {
+		t.Errorf("cached %s (%v), want %+v", data, err, want)
+	}
+
+	if _, err := getLatestRelease(context.Background(), now.Add(releaseCheckTTL-time.Minute)); err != nil {
+		t.Fatalf("getLatestRelease failed: %v", err)
+	}
+	if requests != 1 {
+		t.Errorf("made %d requests within the TTL, want 1", requests)
+	}
+
+	if _, err := getLatestRelease(context.Background(), now.Add(releaseCheckTTL)); err != nil {
+		t.Fatalf("getLatestRelease failed: %v", err)
+	}
+	if requests != 2 {
+		t.Errorf("made %d requests once the TTL passed, want 2", requests)
+	}
+}
+
+func TestGetLatestReleaseFails(t *testing.T) {
+	tests := []struct {
+		status int
+		err    string
+	}{
+		{http.StatusNotFound, "no releases yet"},
+		{http.StatusForbidden, "403 Forbidden"},
+		{http.StatusInternalServerError, "500 Internal Server Error"},
+	}
+	for _, tt := range tests {
+		var requests int
+		releaseServer(t, tt.status, &requests)
+
+		_, err := getLatestRelease(context.Background(), time.Now())
+		if err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("getLatestRelease with status %d returned %v, want an error containing %q", tt.status, err, tt.err)
+		}
+		if _, err := os.Stat(filepath.Join(viper.GetString("CacheDir"), "gum", "latest-release.json")); err == nil {
+			t.Errorf("getLatestRelease cached a failed check (status %d)", tt.status)
+		}
+	}
+}
+
+func TestCheckVersionFormat(t *testing.T) {
+	for _, format := range []string{"default", "json"} {
+		if err := checkVersionFormat(format); err != nil {
+			t.Errorf("checkVersionFormat(%q) = %v, want it accepted", format, err)
+		}
+	}
+	for _, format := range []string{"", "text", "null", "yaml"} {
+		if err := checkVersionFormat(format); err == nil {
+			t.Errorf("checkVersionFormat(%q) accepted it", format)
+		}
+	}
+}
+
+func TestDoVersionCheckJSON(t *testing.T) {
+	var requests int
+	releaseServer(t, http.StatusOK, &requests)
+	info := BuildInfo{Version: "v1.3.2"}
+
+	out := captureStdout(t, func() {
+		if err := doVersionCheck(context.Background(), info, "json", false); err != nil {
+			t.Errorf("doVersionCheck failed: %v", err)
+		}
+	})
+	want := `{"current":"v1.3.2","latest":"v1.4.0","url":"https://github.com/shalomb/gum/releases/tag/v1.4.0","up_to_date":false,"status":"out_of_date"}` + "\n"
+	if out != want {
+		t.Errorf("doVersionCheck printed %q, want %q", out, want)
+	}
+
+	out = captureStdout(t, func() {
+		if err := doVersionCheck(context.Background(), info, "json", true); err != nil {
+			t.Errorf("doVersionCheck failed: %v", err)
+		}
+	})
+	if !strings.HasPrefix(out, "{\n  \"current\": \"v1.3.2\",\n") {
+		t.Errorf("doVersionCheck with --pretty printed %q, want indented JSON", out)
+	}
+}
+
+func TestDoVersionCheckStatus(t *testing.T) {
+	var requests int
+	releaseServer(t, http.StatusOK, &requests)
+
+	tests := []struct {
+		info   BuildInfo
+		status string
+		text   string
+	}{
+		{BuildInfo{Version: "v1.4.0"}, "up_to_date", "is up to date"},
+		{BuildInfo{Version: "v1.5.0"}, "up_to_date", "is up to date"},
+		{BuildInfo{Version: "v1.3.2"}, "out_of_date", "is out of date"},
+		{BuildInfo{Version: "v1.4.0-rc.1"}, "up_to_date", "is up to date"},
+		{BuildInfo{Version: "dev"}, "unknown", "is not a release build"},
+		{BuildInfo{Version: "v1.3.2", Dirty: true}, "unknown", "is not a release build"},
+		{BuildInfo{Version: "v1.3.2-4-gabcdef0"}, "unknown", "is not a release build"},
+		{BuildInfo{Version: "v1.3.2-4-gabcdef0-dirty"}, "unknown", "is not a release build"},
+		{BuildInfo{Version: "v1.3.3-0.20230501090000-abcdef123456"}, "unknown", "is not a release build"},
+		{BuildInfo{Version: "v0.0.0-20230501090000-abcdef123456"}, "unknown", "is not a release build"},
+		{BuildInfo{Version: "v1.3.2+dirty"}, "unknown", "is not a release build"},
+	}
+	for _, tt := range tests {
+		out := captureStdout(t, func() {
+			if err := doVersionCheck(context.Background(), tt.info, "json", false); err != nil {
+				t.Errorf("doVersionCheck failed: %v", err)
+			}
+		})
+		var result versionCheck
+		if err := json.Unmarshal([]byte(out), &result); err != nil {
+			t.Fatalf("invalid JSON %q: %v", out, err)
+		}
+		if result.Status != tt.status || result.UpToDate != (tt.status == "up_to_date") {
+			t.Errorf("checking %+v gave status %q, up_to_date %v; want %q", tt.info, result.Status, result.UpToDate, tt.status)
+		}
+
+		out = captureStdout(t, func() { doVersionCheck(context.Background(), tt.info, "default", false) })
+		if !strings.Contains(out, tt.text) {
+			t.Errorf("checking %+v printed %q, want %q in it", tt.info, out, tt.text)
+		}
+	}
+}
@@ -0,0 +1,142 @@
+/*
+Copyright © 2023 shalomb <s.bhooshi@gmail.com>
+*/
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// widgetCmd represents the widget command
+var widgetCmd = &cobra.Command{
+	Use:   "widget {bash|zsh|fish|tmux}",
+	Short: "Print a shell snippet that binds a key to jump to frequent dirs",
+	Long: `Print a ready-to-source snippet that binds a key (Ctrl-G by default) to
+pick one of the directories listed by "gum dirs" with fzf and jump to it.
+
+  eval "$(gum widget bash)"              # ~/.bashrc
+  eval "$(gum widget zsh)"               # ~/.zshrc
+  gum widget fish | source               # ~/.config/fish/config.fish
+  gum widget tmux > ~/.config/tmux/gum.conf  # then source-file it
+
+The tmux variant opens the picker in a popup and starts a new window in the
+chosen directory. If fzf is not installed, the binding prints a hint instead
+of failing.`,
+	Args:      cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
+	ValidArgs: []string{"bash", "zsh", "fish", "tmux"},
+
+	RunE: func(cmd *cobra.Command, args []string) error {
+		bind, _ := cmd.Flags().GetString("bind")
+		snippet, err := widgetSnippet(args[0], bind)
+		if err != nil {
+			return err
+		}
+		fmt.Print(snippet)
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(widgetCmd)
+
+	widgetCmd.Flags().String("bind", "ctrl-g", "Key to bind, as ctrl-<key> or alt-<key>")
+}
+
+// widgetPicker is the pipeline shared by all snippets. It prints the chosen
+// directory, or nothing if the selection was aborted.
+const widgetPicker = `gum dirs 2>/dev/null | fzf --height=40% --reverse --delimiter='\t' --with-nth=2.. --preview='ls -A {2}' | gum resolve 2>/dev/null`
+
+const widgetMissingFzf = `gum: fzf not found in PATH, see https://github.com/junegunn/fzf`
+
+// widgetSnippet renders the integration snippet for shell with the key
+// binding given in the fzf-style notation ctrl-<key> or alt-<key>.
+func widgetSnippet(shell, bind string) (string, error) {
+	mod, key, err := parseWidgetBind(bind)
+	if err != nil {
+		return "", err
+	}
+
+	switch shell {
+	case "bash":
+		seq := `\C-` + key
+		if mod == "alt" {
+			seq = `\e` + key
+		}
+		return fmt.Sprintf(`__gum_widget() {
+  if ! command -v fzf >/dev/null 2>&1; then
+    echo %q >&2
+    return 1
+  fi
+  local dir
+  dir="$(%s)"
+  [ -n "$dir" ] && cd -- "$dir"
+}
+bind -x '"%s": __gum_widget'
+`, widgetMissingFzf, widgetPicker, seq), nil
+
+	case "zsh":
+		seq := "^" + strings.ToUpper(key)
+		if mod == "alt" {
+			seq = "^[" + key
+		}
+		return fmt.Sprintf(`__gum_widget() {
+  if ! (( $+commands[fzf] )); then
+    zle -M %q
+    return 1
+  fi
+  local dir
+  dir="$(%s)"
+  [[ -n "$dir" ]] && builtin cd -- "$dir"
+  zle reset-prompt
+}
+zle -N __gum_widget
+bindkey '%s' __gum_widget
+`, widgetMissingFzf, widgetPicker, seq), nil
+
+	case "fish":
+		seq := `\c` + key
+		if mod == "alt" {
+			seq = `\e` + key
+		}
+		return fmt.Sprintf(`function __gum_widget
+    if not command -q fzf
+        echo %q >&2
+        return 1
+    end
+    set -l dir (%s)
+    test -n "$dir"; and cd -- $dir
+    commandline -f repaint
+end
+bind %s __gum_widget
+`, widgetMissingFzf, widgetPicker, seq), nil
+
+	case "tmux":
+		seq := "C-" + key
+		if mod == "alt" {
+			seq = "M-" + key
+		}
+		script := fmt.Sprintf(`command -v fzf >/dev/null 2>&1 || { echo %q; read -r _; exit 1; }; dir="$(%s)"; [ -n "$dir" ] && tmux new-window -c "$dir"`,
+			widgetMissingFzf, widgetPicker)
+		return fmt.Sprintf("bind-key -n %s display-popup -E %s\n", seq, tmuxQuote(script)), nil
+	}
+
+	return "", fmt.Errorf("unsupported shell %q: expected one of bash, zsh, fish, tmux", shell)
+}
+
+// parseWidgetBind splits a key binding such as "ctrl-g" or "alt-j" into its
+// modifier and a single lower-case key.
+func parseWidgetBind(bind string) (mod, key string, err error) {
+	mod, key, ok := strings.Cut(strings.ToLower(bind), "-")
+	if !ok || (mod != "ctrl" && mod != "alt") || len(key) != 1 || key[0] < 'a' || key[0] > 'z' {
+		return "", "", fmt.Errorf("invalid --bind %q: expected ctrl-<key> or alt-<key>, e.g. ctrl-g", bind)
+	}
+	return mod, key, nil
+}
+
+// tmuxQuote wraps s in single quotes for a tmux configuration file.
+func tmuxQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
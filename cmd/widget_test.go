@@ -0,0 +1,141 @@
+package cmd
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// widgetBinds are the --bind values the snippets are checked with.
+var widgetBinds = []string{"ctrl-g", "alt-j"}
+
+// gumInvocation matches gum and its arguments in a shell snippet, up to the
+// first redirection, pipe, or end of the command. Messages such as "gum:
+// fzf not found" have no arguments and do not match.
+var gumInvocation = regexp.MustCompile(`\bgum((?:[ \t]+[^\s|;&<>()"'0-9][^\s|;&<>()"']*)+)`)
+
+func TestWidgetSnippetsParse(t *testing.T) {
+	for _, shell := range widgetCmd.ValidArgs {
+		for _, bind := range widgetBinds {
+			t.Run(shell+"/"+bind, func(t *testing.T) {
+				snippet, err := widgetSnippet(shell, bind)
+				if err != nil {
+					t.Fatalf("widgetSnippet(%q, %q) failed: %v", shell, bind, err)
+				}
+				checkWidgetSyntax(t, shell, snippet)
+			})
+		}
+	}
+}
+
+// checkWidgetSyntax parses snippet with shell, or tmux, without running it,
+// skipping the test if that is not installed.
+func checkWidgetSyntax(t *testing.T, shell, snippet string) {
+	t.Helper()
+
+	var cmd *exec.Cmd
+	switch shell {
+	case "bash", "zsh":
+		cmd = exec.Command(shell, "-n")
+	case "fish":
+		cmd = exec.Command("fish", "--no-execute")
+	case "tmux":
+		// The popup runs its command with sh, so check that as well.
+		script, ok := tmuxPopupScript(snippet)
+		if !ok {
+			t.Fatalf("tmux snippet %q does not bind a popup", snippet)
+		}
+		if out, err := exec.Command("sh", "-n", "-c", script).CombinedOutput(); err != nil {
+			t.Errorf("sh cannot parse the popup command %q: %v\n%s", script, err, out)
+		}
+
+		conf := filepath.Join(t.TempDir(), "gum.conf")
+		if err := os.WriteFile(conf, []byte(snippet), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		cmd = exec.Command("tmux", "-L", "gum-test", "-f", os.DevNull, "start-server", ";", "source-file", "-n", conf)
+		cmd.Env = append(os.Environ(), "TMUX_TMPDIR="+t.TempDir(), "TMUX=")
+	default:
+		t.Fatalf("no syntax check for %v", shell)
+	}
+
+	if _, err := exec.LookPath(cmd.Args[0]); err != nil {
+		t.Skipf("%v is not installed", cmd.Args[0])
+	}
+	cmd.Stdin = strings.NewReader(snippet)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Errorf("%v cannot parse the snippet: %v\n%s\n%s", cmd.Args[0], err, out, snippet)
+	}
+}
+
+// tmuxPopupScript returns the command of the display-popup a tmux snippet
+// binds, unquoted.
+func tmuxPopupScript(snippet string) (string, bool) {
+	_, quoted, ok := strings.Cut(strings.TrimSpace(snippet), " display-popup -E ")
+	if !ok || len(quoted) < 2 || quoted[0] != '\'' || quoted[len(quoted)-1] != '\'' {
+		return "", false
+	}
+	return strings.ReplaceAll(quoted[1:len(quoted)-1], `'\''`, `'`), true
+}
+
+func TestWidgetSnippetsUseGumCommands(t *testing.T) {
+	for _, shell := range widgetCmd.ValidArgs {
+		for _, bind := range widgetBinds {
+			snippet, err := widgetSnippet(shell, bind)
+			if err != nil {
+				t.Fatalf("widgetSnippet(%q, %q) failed: %v", shell, bind, err)
+			}
+
+			invocations := gumInvocation.FindAllStringSubmatch(snippet, -1)
+			if len(invocations) == 0 {
+				t.Errorf("%v snippet runs no gum commands:\n%s", shell, snippet)
+			}
+			for _, m := range invocations {
+				if err := checkGumInvocation(strings.Fields(m[1])); err != "" {
+					t.Errorf("%v snippet runs %q: %v", shell, m[0], err)
+				}
+			}
+		}
+	}
+}
+
+// checkGumInvocation describes what is wrong with running gum with args, if
+// anything: a command or flag that does not exist.
+func checkGumInvocation(args []string) string {
+	cmd, _, err := rootCmd.Find(args)
+	if err != nil || cmd == rootCmd {
+		return "no such command"
+	}
+	for _, arg := range args {
+		if !strings.HasPrefix(arg, "-") {
+			continue
+		}
+		name, _, _ := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		var found bool
+		if strings.HasPrefix(arg, "--") {
+			found = cmd.Flags().Lookup(name) != nil || cmd.InheritedFlags().Lookup(name) != nil
+		} else {
+			found = cmd.Flags().ShorthandLookup(name[:1]) != nil || cmd.InheritedFlags().ShorthandLookup(name[:1]) != nil
+		}
+		if !found {
+			return "no such flag of gum " + cmd.Name() + ": " + arg
+		}
+	}
+	return ""
+}
+
+func TestCheckGumInvocation(t *testing.T) {
+	for _, args := range [][]string{{"dirs"}, {"dirs", "-0"}, {"dirs", "--format=json"}, {"resolve", "--width", "40"}} {
+		if err := checkGumInvocation(args); err != "" {
+			t.Errorf("checkGumInvocation(%q) = %q, want no error", args, err)
+		}
+	}
+	for _, args := range [][]string{{"nonesuch"}, {"dirs", "--nonesuch"}, {"dirs", "-Z"}} {
+		if err := checkGumInvocation(args); err == "" {
+			t.Errorf("checkGumInvocation(%q) found nothing wrong", args)
+		}
+	}
+}
@@ -0,0 +1,266 @@
+/*
+Copyright © 2023 shalomb <s.bhooshi@gmail.com>
+*/
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// worktreeCmd represents the worktree command
+var worktreeCmd = &cobra.Command{
+	Use:   "worktree",
+	Short: "Add, list and remove git worktrees of projects",
+	Long: `Add, list and remove linked git worktrees of projects, placed by a
+convention so that there is no deciding where each one goes.
+
+New worktrees go where the worktree.path setting says, relative to the
+directory holding the project. {project} stands for the project's name
+and {branch} for the branch, with slashes replaced by dashes:
+
+  worktree:
+    path: "{project}-wt/{branch}"   # the default
+
+Worktrees under the project directories are listed by gum projects like
+any other project, unless discovery.worktrees is false. With the default
+worktree.path, next to the project, they are listed straight away;
+elsewhere they may only be listed once updatedb has indexed them, or
+with --no-locate.`,
+}
+
+// worktreeAddCmd represents the worktree add command
+var worktreeAddCmd = &cobra.Command{
+	Use:   "add <project> <branch>",
+	Short: "Check a branch of a project out in a new worktree",
+	Long: `Check a branch of a project out in a new linked worktree and print its
+path, so that
+
+  cd "$(gum worktree add gum fix-parser)"
+
+lands in it. The project is matched as by gum edit. A branch that exists
+neither locally nor on origin is created from the default branch.
+--path puts the worktree somewhere other than worktree.path says.`,
+	Args:         cobra.ExactArgs(2),
+	SilenceUsage: true,
+
+	RunE: func(cmd *cobra.Command, args []string) error {
+		path, _ := cmd.Flags().GetString("path")
+		return doWorktreeAdd(cmd.Context(), args[0], args[1], path)
+	},
+}
+
+// worktreeListCmd represents the worktree list command
+var worktreeListCmd = &cobra.Command{
+	Use:   "list [project]",
+	Short: "List the worktrees of a project, or all linked worktrees",
+	Long: `List the worktrees of a project, its main working tree first, as git
+knows them. Without a project, list the linked worktrees among all
+projects.`,
+	Args:         cobra.MaximumNArgs(1),
+	SilenceUsage: true,
+
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var query string
+		if len(args) > 0 {
+			query = args[0]
+		}
+		return doWorktreeList(cmd.Context(), query)
+	},
+}
+
+// worktreeRemoveCmd represents the worktree remove command
+var worktreeRemoveCmd = &cobra.Command{
+	Use:   "remove <worktree>",
+	Short: "Remove a linked worktree",
+	Long: `Remove a linked worktree, given by path or matched by name as by gum
+edit. A worktree with uncommitted changes, including untracked files, is
+not removed unless --force is given. The branch it had checked out is
+kept.`,
+	Args:         cobra.ExactArgs(1),
+	SilenceUsage: true,
+
+	RunE: func(cmd *cobra.Command, args []string) error {
+		force, _ := cmd.Flags().GetBool("force")
+		return doWorktreeRemove(cmd.Context(), args[0], force)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(worktreeCmd)
+	worktreeCmd.AddCommand(worktreeAddCmd, worktreeListCmd, worktreeRemoveCmd)
+
+	worktreeAddCmd.Flags().String("path", "", "Create the worktree in this directory")
+	worktreeRemoveCmd.Flags().Bool("force", false, "Remove the worktree even if it has uncommitted changes")
+
+	viper.SetDefault("worktree.path", "{project}-wt/{branch}")
+}
+
+func doWorktreeAdd(ctx context.Context, query, branch, path string) error {
+	projects, err := findProjects(ctx, runner)
+	if err != nil {
+		return err
+	}
+	project, err := resolveProject(query, projects)
+	if err != nil {
+		return err
+	}
+	if err := checkBranchName(ctx, project, branch); err != nil {
+		return err
+	}
+
+	if path == "" {
+		path = worktreePath(project, branch)
+	} else if path, err = filepath.Abs(expandPath(path)); err != nil {
+		return err
+	}
+	if _, err := os.Lstat(path); err == nil {
+		return fmt.Errorf("%v already exists", tildePath(path))
+	}
+
+	args := []string{"worktree", "add", path, branch}
+	if !gitRefExists(ctx, project, "refs/heads/"+branch) && !gitRefExists(ctx, project, "refs/remotes/origin/"+branch) {
+		args = []string{"worktree", "add", "-b", branch, path, gitDefaultBranch(ctx, project)}
+	}
+	log.Debugf("running git %v in %v", args, project)
+	if _, stderr, err := runner.Run(ctx, project, "git", args...); err != nil {
+		return fmt.Errorf("error adding worktree for %v: %v", branch, strings.TrimSpace(string(stderr)))
+	}
+
+	if !withinProjectDirs(path) {
+		log.Warnf("%v is outside the project directories, so gum projects will not list it", tildePath(path))
+	}
+	fmt.Println(path)
+	return nil
+}
+
+func doWorktreeList(ctx context.Context, query string) error {
+	projects, err := findProjects(ctx, runner)
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(os.Stdout)
+	defer w.Flush()
+
+	if query == "" {
+		for _, p := range projects {
+			if isGitWorktree(p) {
+				fmt.Fprintln(w, p)
+			}
+		}
+		return nil
+	}
+
+	project, err := resolveProject(query, projects)
+	if err != nil {
+		return err
+	}
+	stdout, stderr, err := runner.Run(ctx, project, "git", "worktree", "list", "--porcelain")
+	if err != nil {
+		return fmt.Errorf("error listing worktrees of %v: %v", tildePath(project), strings.TrimSpace(string(stderr)))
+	}
+	for _, line := range strings.Split(string(stdout), "\n") {
+		if path, ok := strings.CutPrefix(line, "worktree "); ok {
+			fmt.Fprintln(w, path)
+		}
+	}
+	return nil
+}
+
+func doWorktreeRemove(ctx context.Context, query string, force bool) error {
+	var worktree string
+	if strings.HasPrefix(query, ".") || strings.HasPrefix(query, "~/") || filepath.IsAbs(query) {
+		path, err := filepath.Abs(expandPath(query))
+		if err != nil {
+			return err
+		}
+		worktree = path
+	} else {
+		projects, err := findProjects(ctx, runner)
+		if err != nil {
+			return err
+		}
+		if worktree, err = resolveProject(query, projects); err != nil {
+			return err
+		}
+	}
+
+	_, common, err := gitDirs(worktree)
+	if err != nil || !isGitWorktree(worktree) {
+		return fmt.Errorf("%v is not a linked worktree", tildePath(worktree))
+	}
+
+	if !force {
+		dirty, err := gitDirty(ctx, runner, worktree)
+		if err != nil {
+			return fmt.Errorf("error checking %v for changes: %w", tildePath(worktree), err)
+		}
+		if dirty {
+			return fmt.Errorf("%v has uncommitted changes; use --force to remove it anyway", tildePath(worktree))
+		}
+	}
+
+	// Run from the main repository, as a worktree cannot remove itself.
+	args := []string{"worktree", "remove", worktree}
+	if force {
+		args = append(args, "--force")
+	}
+	if _, stderr, err := runner.Run(ctx, common, "git", args...); err != nil {
+		return fmt.Errorf("error removing worktree %v: %v", tildePath(worktree), strings.TrimSpace(string(stderr)))
+	}
+	return nil
+}
+
+// worktreePath returns where the worktree.path setting puts a worktree of
+// project for branch.
+func worktreePath(project, branch string) string {
+	path := strings.NewReplacer(
+		"{project}", filepath.Base(project),
+		"{branch}", strings.ReplaceAll(branch, "/", "-"),
+	).Replace(viper.GetString("worktree.path"))
+
+	path = expandPath(path)
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(filepath.Dir(project), path)
+	}
+	return path
+}
+
+// checkBranchName makes sure that branch is a valid branch name, and not
+// an option or a shorthand such as @{-1} that git would expand.
+func checkBranchName(ctx context.Context, repo, branch string) error {
+	if strings.HasPrefix(branch, "-") {
+		return fmt.Errorf("invalid branch %q: expected a name not starting with -", branch)
+	}
+	stdout, _, err := runner.Run(ctx, repo, "git", "check-ref-format", "--branch", branch)
+	if err != nil || string(bytes.TrimSpace(stdout)) != branch {
+		return fmt.Errorf("invalid branch %q: not a valid branch name", branch)
+	}
+	return nil
+}
+
+// gitRefExists reports whether the repository at repo has the ref.
+func gitRefExists(ctx context.Context, repo, ref string) bool {
+	_, _, err := runner.Run(ctx, repo, "git", "rev-parse", "--verify", "--quiet", ref)
+	return err == nil
+}
+
+// gitDefaultBranch returns the branch new branches of the repository at
+// repo start from: origin's default branch if known, else HEAD.
+func gitDefaultBranch(ctx context.Context, repo string) string {
+	stdout, _, err := runner.Run(ctx, repo, "git", "symbolic-ref", "--quiet", "--short", "refs/remotes/origin/HEAD")
+	if branch := string(bytes.TrimSpace(stdout)); err == nil && branch != "" {
+		return branch
+	}
+	return "HEAD"
+}
@@ -0,0 +1,198 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/shalomb/gum/internal/execx"
+	"github.com/spf13/viper"
+)
+
+func TestCheckBranchName(t *testing.T) {
+	saved := runner
+	t.Cleanup(func() { runner = saved })
+	runner = &execx.Fake{Responses: []execx.FakeResponse{
+		{Argv: []string{"git", "check-ref-format", "--branch", "fix/parser"}, Stdout: "fix/parser\n"},
+		{Argv: []string{"git", "check-ref-format", "--branch", "@{-1}"}, Stdout: "main\n"},
+		{Argv: []string{"git", "check-ref-format"}, Err: exitError(t, 128)},
+	}}
+
+	tests := map[string]bool{
+		"fix/parser": true,
+		"-f":         false,
+		"--force":    false,
+		"a b":        false,
+		"@{-1}":      false,
+	}
+	for branch, valid := range tests {
+		if err := checkBranchName(context.Background(), "/repo", branch); (err == nil) != valid {
+			t.Errorf("checkBranchName(%q) = %v, want valid %v", branch, err, valid)
+		}
+	}
+	for _, call := range runner.(*execx.Fake).Calls() {
+		if strings.HasPrefix(call[len(call)-1], "-") {
+			t.Errorf("ran %q; want names starting with - refused before running git", call)
+		}
+	}
+}
+
+func TestWorktreePath(t *testing.T) {
+	t.Cleanup(func() { viper.Set("worktree.path", nil) })
+
+	tests := []struct {
+		setting string
+		want    string
+	}{
+		{"", "/work/gum-wt/fix-parser"},
+		{"{project}.{branch}", "/work/gum.fix-parser"},
+		{"/tmp/wt/{project}/{branch}", "/tmp/wt/gum/fix-parser"},
+	}
+	for _, tt := range tests {
+		if tt.setting != "" {
+			viper.Set("worktree.path", tt.setting)
+		}
+		if got := worktreePath("/work/gum", "fix/parser"); got != tt.want {
+			t.Errorf("worktreePath with %q = %q, want %q", tt.setting, got, tt.want)
+		}
+	}
+}
+
+// git runs git in dir for a test, failing it if git fails.
+func git(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	args = append([]string{"-c", "user.name=gum", "-c", "user.email=gum@example.com"}, args...)
+	if out, err := exec.Command("git", append([]string{"-C", dir}, args...)...).CombinedOutput(); err != nil {
+		t.Fatalf("git %v failed: %v: %s", args, err, out)
+	}
+}
+
+func TestDoWorktreeRemove(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	root := t.TempDir()
+	repo, wt := filepath.Join(root, "repo"), filepath.Join(root, "repo-wt", "topic")
+	git(t, root, "init", "--quiet", repo)
+	git(t, repo, "commit", "--quiet", "--allow-empty", "-m", "init")
+	git(t, repo, "worktree", "add", "--quiet", "-b", "topic", wt)
+
+	if err := doWorktreeRemove(context.Background(), repo, false); err == nil || !strings.Contains(err.Error(), "not a linked worktree") {
+		t.Errorf("removing the main worktree returned %v; want it refused", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(wt, "notes.txt"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := doWorktreeRemove(context.Background(), wt, false); err == nil || !strings.Contains(err.Error(), "uncommitted changes") {
+		t.Errorf("removing a dirty worktree returned %v; want it refused", err)
+	}
+	if _, err := os.Stat(wt); err != nil {
+		t.Fatalf("dirty worktree was removed: %v", err)
+	}
+
+	if err := doWorktreeRemove(context.Background(), wt, true); err != nil {
+		t.Fatalf("removing a dirty worktree with --force failed: %v", err)
+	}
+	if _, err := os.Stat(wt); !os.IsNotExist(err) {
+		t.Errorf("worktree %v still exists after removal", wt)
+	}
+}
+
+// gitOutput runs git in dir for a test and returns what it printed,
+// trimmed.
+func gitOutput(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	out, err := exec.Command("git", append([]string{"-C", dir}, args...)...).Output()
+	if err != nil {
+		t.Fatalf("git %v failed: %v", args, err)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func TestDoWorktreeAddAndList(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	home := withHome(t)
+	withoutLocate(t)
+	withProjects(t, "~/src")
+	t.Cleanup(func() { viper.Set("worktree.path", nil) })
+
+	// app is a clone whose checkout is on a feature branch, so that a
+	// branch made from HEAD would differ from one made from origin's
+	// default branch. solo has no remote at all.
+	upstream := filepath.Join(home, "upstream")
+	app, solo := filepath.Join(home, "src", "app"), filepath.Join(home, "src", "solo")
+	git(t, home, "init", "--quiet", "--initial-branch", "main", upstream)
+	git(t, upstream, "commit", "--quiet", "--allow-empty", "-m", "init")
+	git(t, upstream, "branch", "release")
+	git(t, home, "clone", "--quiet", upstream, app)
+	git(t, app, "checkout", "--quiet", "-b", "feature")
+	git(t, app, "commit", "--quiet", "--allow-empty", "-m", "feature work")
+	git(t, home, "init", "--quiet", "--initial-branch", "trunk", solo)
+	git(t, solo, "commit", "--quiet", "--allow-empty", "-m", "init")
+
+	if got := gitDefaultBranch(context.Background(), app); got != "origin/main" {
+		t.Errorf("gitDefaultBranch of a clone = %q, want origin/main", got)
+	}
+	if got := gitDefaultBranch(context.Background(), solo); got != "HEAD" {
+		t.Errorf("gitDefaultBranch without a remote = %q, want HEAD", got)
+	}
+
+	add := func(project, branch string) string {
+		t.Helper()
+		out := captureStdout(t, func() {
+			if err := doWorktreeAdd(context.Background(), project, branch, ""); err != nil {
+				t.Errorf("doWorktreeAdd(%q, %q) failed: %v", project, branch, err)
+			}
+		})
+		return strings.TrimSuffix(out, "\n")
+	}
+
+	topic := add("app", "fix/topic")
+	if want := filepath.Join(home, "src", "app-wt", "fix-topic"); topic != want {
+		t.Errorf("doWorktreeAdd printed %q, want %q", topic, want)
+	}
+	if got, want := gitOutput(t, topic, "rev-parse", "HEAD"), gitOutput(t, app, "rev-parse", "origin/main"); got != want {
+		t.Errorf("new branch starts at %v, want origin's default branch at %v", got, want)
+	}
+	if got := gitOutput(t, topic, "branch", "--show-current"); got != "fix/topic" {
+		t.Errorf("worktree has %q checked out, want fix/topic", got)
+	}
+
+	release := add("app", "release")
+	if got := gitOutput(t, release, "rev-parse", "--abbrev-ref", "@{upstream}"); got != "origin/release" {
+		t.Errorf("release tracks %q, want origin/release", got)
+	}
+
+	other := add("solo", "other")
+	if got, want := gitOutput(t, other, "rev-parse", "HEAD"), gitOutput(t, solo, "rev-parse", "HEAD"); got != want {
+		t.Errorf("new branch without a remote starts at %v, want HEAD at %v", got, want)
+	}
+
+	if err := doWorktreeAdd(context.Background(), "app", "fix/topic", ""); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("adding the same worktree again returned %v, want it refused", err)
+	}
+
+	out := captureStdout(t, func() {
+		if err := doWorktreeList(context.Background(), "app"); err != nil {
+			t.Errorf("doWorktreeList failed: %v", err)
+		}
+	})
+	if want := app + "\n" + topic + "\n" + release + "\n"; out != want {
+		t.Errorf("doWorktreeList(app) printed %q, want %q", out, want)
+	}
+
+	out = captureStdout(t, func() {
+		if err := doWorktreeList(context.Background(), ""); err != nil {
+			t.Errorf("doWorktreeList failed: %v", err)
+		}
+	})
+	if want := topic + "\n" + release + "\n" + other + "\n"; out != want {
+		t.Errorf("doWorktreeList() printed %q, want %q", out, want)
+	}
+}
@@ -0,0 +1,147 @@
+// Package execx runs the external commands gum shells out to behind an
+// interface, so that callers can be exercised without the real programs.
+package execx
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+// Runner runs programs to completion.
+type Runner interface {
+	// Run runs name with args in dir (the current directory if empty) and
+	// returns what it wrote. A non-zero exit is reported as an
+	// *exec.ExitError.
+	Run(ctx context.Context, dir, name string, args ...string) (stdout, stderr []byte, err error)
+
+	// Stream runs name with args in dir like Run, but connected to the
+	// streams of s rather than capturing its output, for programs whose
+	// output is for the user as it happens.
+	Stream(ctx context.Context, s Streams, dir, name string, args ...string) error
+}
+
+// Streams are what a program run by Stream reads from and writes to, and
+// its environment. Nil streams are the null device, and a nil Env is the
+// environment of the current process.
+type Streams struct {
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+	Env    []string
+}
+
+// Exec is the Runner that runs real processes.
+type Exec struct{}
+
+// Run implements Runner.
+func (Exec) Run(ctx context.Context, dir, name string, args ...string) ([]byte, []byte, error) {
+	var stdout, stderr bytes.Buffer
+
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = dir
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	return stdout.Bytes(), stderr.Bytes(), err
+}
+
+// Stream implements Runner.
+func (Exec) Stream(ctx context.Context, s Streams, dir, name string, args ...string) error {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = dir
+	cmd.Stdin = s.Stdin
+	cmd.Stdout = s.Stdout
+	cmd.Stderr = s.Stderr
+	cmd.Env = s.Env
+	return cmd.Run()
+}
+
+// FakeResponse is the canned result for commands whose argv starts with
+// Argv, run in Dir if it is not empty. Effect, if set, is called with the
+// directory and command line before the result is returned, to do what
+// the program would, such as create files.
+type FakeResponse struct {
+	Argv   []string
+	Dir    string
+	Stdout string
+	Stderr string
+	Err    error
+	Effect func(dir string, argv []string)
+}
+
+// Fake is a scriptable Runner for tests. Each call is answered by the first
+// response whose Argv is a prefix of the command line and whose Dir, if
+// set, is the directory it runs in, and recorded in Calls. Fake is safe
+// for concurrent use.
+type Fake struct {
+	Responses []FakeResponse
+
+	mu    sync.Mutex
+	calls [][]string
+}
+
+// Run implements Runner.
+func (f *Fake) Run(ctx context.Context, dir, name string, args ...string) ([]byte, []byte, error) {
+	r, err := f.respond(dir, append([]string{name}, args...))
+	if err != nil {
+		return nil, nil, err
+	}
+	return []byte(r.Stdout), []byte(r.Stderr), r.Err
+}
+
+// Stream implements Runner, writing the canned output to the streams of s.
+func (f *Fake) Stream(ctx context.Context, s Streams, dir, name string, args ...string) error {
+	r, err := f.respond(dir, append([]string{name}, args...))
+	if err != nil {
+		return err
+	}
+	if s.Stdout != nil {
+		io.WriteString(s.Stdout, r.Stdout)
+	}
+	if s.Stderr != nil {
+		io.WriteString(s.Stderr, r.Stderr)
+	}
+	return r.Err
+}
+
+// respond records a call and returns the response to it.
+func (f *Fake) respond(dir string, argv []string) (FakeResponse, error) {
+	f.mu.Lock()
+	f.calls = append(f.calls, argv)
+	f.mu.Unlock()
+
+	for _, r := range f.Responses {
+		if hasPrefix(argv, r.Argv) && (r.Dir == "" || r.Dir == dir) {
+			if r.Effect != nil {
+				r.Effect(dir, argv)
+			}
+			return r, nil
+		}
+	}
+	return FakeResponse{}, fmt.Errorf("execx: no fake response for %q", strings.Join(argv, " "))
+}
+
+// Calls returns the command lines run so far.
+func (f *Fake) Calls() [][]string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([][]string(nil), f.calls...)
+}
+
+func hasPrefix(argv, prefix []string) bool {
+	if len(prefix) > len(argv) {
+		return false
+	}
+	for i := range prefix {
+		if argv[i] != prefix[i] {
+			return false
+		}
+	}
+	return true
+}
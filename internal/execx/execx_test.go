@@ -0,0 +1,110 @@
+package execx
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExecRun(t *testing.T) {
+	dir := t.TempDir()
+	stdout, stderr, err := Exec{}.Run(context.Background(), dir, "sh", "-c", "pwd; echo oops >&2; exit 3")
+
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != 3 {
+		t.Errorf("Run returned %v, want exit status 3", err)
+	}
+	if got := strings.TrimSpace(string(stdout)); !strings.HasSuffix(got, dir) {
+		t.Errorf("Run ran in %q, want %q", got, dir)
+	}
+	if got := string(stderr); got != "oops\n" {
+		t.Errorf("Run stderr = %q, want %q", got, "oops\n")
+	}
+}
+
+func TestFake(t *testing.T) {
+	failure := errors.New("failure")
+	f := &Fake{Responses: []FakeResponse{
+		{Argv: []string{"git", "status"}, Dir: "/a", Stdout: " M file\n"},
+		{Argv: []string{"git", "status"}, Err: failure},
+		{Argv: []string{"find"}, Stdout: "found\n", Stderr: "warning\n"},
+	}}
+	ctx := context.Background()
+
+	if stdout, _, err := f.Run(ctx, "/a", "git", "status", "--porcelain"); string(stdout) != " M file\n" || err != nil {
+		t.Errorf("git status in /a = %q, %v; want the response for /a", stdout, err)
+	}
+	if _, _, err := f.Run(ctx, "/b", "git", "status", "--porcelain"); err != failure {
+		t.Errorf("git status in /b returned %v, want %v", err, failure)
+	}
+	if stdout, stderr, _ := f.Run(ctx, "", "find", "-L", "/"); string(stdout) != "found\n" || string(stderr) != "warning\n" {
+		t.Errorf("find = %q, %q; want the canned output", stdout, stderr)
+	}
+	if _, _, err := f.Run(ctx, "", "git", "log"); err == nil || !strings.Contains(err.Error(), `"git log"`) {
+		t.Errorf("unexpected command returned %v, want an error naming it", err)
+	}
+
+	want := [][]string{
+		{"git", "status", "--porcelain"},
+		{"git", "status", "--porcelain"},
+		{"find", "-L", "/"},
+		{"git", "log"},
+	}
+	if got := f.Calls(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Calls() = %q, want %q", got, want)
+	}
+}
+
+func TestExecStream(t *testing.T) {
+	dir := t.TempDir()
+	var stdout, stderr strings.Builder
+	s := Streams{
+		Stdin:  strings.NewReader("input\n"),
+		Stdout: &stdout,
+		Stderr: &stderr,
+		Env:    []string{"GREETING=hello"},
+	}
+	err := Exec{}.Stream(context.Background(), s, dir, "sh", "-c", `pwd; cat; echo "$GREETING" >&2; exit 3`)
+
+	var exitErr *exec.ExitError
+	if !errors.As(err, &exitErr) || exitErr.ExitCode() != 3 {
+		t.Errorf("Stream returned %v, want exit status 3", err)
+	}
+	if lines := strings.Split(stdout.String(), "\n"); !strings.HasSuffix(lines[0], dir) || lines[1] != "input" {
+		t.Errorf("Stream wrote %q, want the directory %v and the input", stdout.String(), dir)
+	}
+	if got := stderr.String(); got != "hello\n" {
+		t.Errorf("Stream stderr = %q, want %q", got, "hello\n")
+	}
+}
+
+func TestFakeStream(t *testing.T) {
+	var ran []string
+	f := &Fake{Responses: []FakeResponse{{
+		Argv:   []string{"git", "clone"},
+		Stderr: "Cloning...\n",
+		Err:    errors.New("failure"),
+		Effect: func(dir string, argv []string) { ran = append(ran, dir+": "+strings.Join(argv, " ")) },
+	}}}
+
+	var stderr strings.Builder
+	err := f.Stream(context.Background(), Streams{Stderr: &stderr}, "/a", "git", "clone", "url", "dir")
+	if err == nil || err.Error() != "failure" {
+		t.Errorf("Stream returned %v, want the canned error", err)
+	}
+	if stderr.String() != "Cloning...\n" {
+		t.Errorf("Stream wrote %q to stderr, want the canned output", stderr.String())
+	}
+	if want := []string{"/a: git clone url dir"}; !reflect.DeepEqual(ran, want) {
+		t.Errorf("Effect ran for %q, want %q", ran, want)
+	}
+	if err := f.Stream(context.Background(), Streams{}, "", "git", "pull"); err == nil {
+		t.Errorf("Stream of an unexpected command succeeded")
+	}
+	if got, want := f.Calls(), [][]string{{"git", "clone", "url", "dir"}, {"git", "pull"}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Calls() = %q, want %q", got, want)
+	}
+}
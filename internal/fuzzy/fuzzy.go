@@ -0,0 +1,168 @@
+// Package fuzzy scores how well a half-remembered query matches a name, for
+// commands that look projects up by name.
+package fuzzy
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Distance returns the edit distance between a and b: the number of
+// single-rune insertions, deletions, substitutions and swaps of adjacent
+// runes needed to turn one into the other. Swaps count as one edit so
+// that typos such as "gmu" for "gum" stay close.
+func Distance(a, b string) int {
+	s, t := []rune(a), []rune(b)
+
+	// d[i][j] is the distance between s[:i] and t[:j].
+	d := make([][]int, len(s)+1)
+	for i := range d {
+		d[i] = make([]int, len(t)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+
+	for i := 1; i <= len(s); i++ {
+		for j := 1; j <= len(t); j++ {
+			cost := 1
+			if s[i-1] == t[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && s[i-1] == t[j-2] && s[i-2] == t[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(s)][len(t)]
+}
+
+// Match is how well a query matches a name, with the score of each way of
+// matching, all from 0 (no match) to 1.
+type Match struct {
+	// Score is the best of the components below.
+	Score float64
+	// Exact is 1 if the query equals the name, ignoring case.
+	Exact float64
+	// Substring is at least 0.75 if the name contains the query, more the
+	// larger the share of the name it covers.
+	Substring float64
+	// Words is at least 0.7 if the query is made of prefixes of the
+	// words of the name, in order, more the more words it covers: "pdp"
+	// and "data-pipe" both match "platform-data-pipeline".
+	Words float64
+	// Edit falls with the edit distance relative to the longer of the
+	// query and the name.
+	Edit float64
+}
+
+// Score rates how well query matches name, case-insensitively, from 0 (no
+// resemblance) to 1 (equal). See Match for how.
+func Score(query, name string) float64 {
+	return Explain(query, name).Score
+}
+
+// Explain scores how well query matches name, case-insensitively. Only an
+// exact match scores 1.
+func Explain(query, name string) Match {
+	var m Match
+	q, n := strings.ToLower(query), strings.ToLower(name)
+	if q == "" || n == "" {
+		return m
+	}
+
+	if q == n {
+		m.Exact = 1
+	}
+	ql, nl := len([]rune(q)), len([]rune(n))
+	if strings.Contains(n, q) {
+		m.Substring = 0.75 + 0.25*float64(ql)/float64(nl)
+	}
+	words := Words(name)
+	if used := wordPrefixes(strings.Join(Words(query), ""), words); used > 0 {
+		m.Words = 0.7 + 0.2*float64(used)/float64(len(words))
+	}
+	m.Edit = 1 - float64(Distance(q, n))/float64(max(ql, nl))
+
+	m.Score = max(m.Exact, m.Substring, m.Words, m.Edit)
+	return m
+}
+
+// Words splits name into lower-case words at punctuation, spaces, changes
+// between letters and digits, and camelCase humps: "platformDataPipeline",
+// "platform_data-pipeline" and "XMLParser2" give the words "platform",
+// "data", "pipeline" and "xml", "parser", "2".
+func Words(name string) []string {
+	var words []string
+	var word []rune
+	flush := func() {
+		if len(word) > 0 {
+			words = append(words, strings.ToLower(string(word)))
+			word = word[:0]
+		}
+	}
+
+	r := []rune(name)
+	for i, c := range r {
+		if !unicode.IsLetter(c) && !unicode.IsDigit(c) {
+			flush()
+			continue
+		}
+		if i > 0 && len(word) > 0 {
+			prev := r[i-1]
+			switch {
+			case unicode.IsDigit(c) != unicode.IsDigit(prev),
+				unicode.IsUpper(c) && unicode.IsLower(prev),
+				unicode.IsUpper(c) && unicode.IsUpper(prev) && i+1 < len(r) && unicode.IsLower(r[i+1]):
+				flush()
+			}
+		}
+		word = append(word, c)
+	}
+	flush()
+	return words
+}
+
+// wordPrefixes returns the largest number of words that q can be split
+// into non-empty prefixes of, taking the words in order but not
+// necessarily consecutively, or 0 if it cannot be split so.
+func wordPrefixes(q string, words []string) int {
+	if q == "" {
+		return 0
+	}
+
+	// best[i][j] memoises the result for q[i:] and words[j:]: -1 means no
+	// split, and unknown not yet computed.
+	const unknown = -2
+	best := make([][]int, len(q)+1)
+	for i := range best {
+		best[i] = make([]int, len(words)+1)
+		for j := range best[i] {
+			best[i][j] = unknown
+		}
+	}
+
+	var split func(i, j int) int
+	split = func(i, j int) int {
+		if i == len(q) {
+			return 0
+		}
+		if best[i][j] != unknown {
+			return best[i][j]
+		}
+		result := -1
+		for k := j; k < len(words); k++ {
+			for l := 1; l <= len(words[k]) && i+l <= len(q) && q[i+l-1] == words[k][l-1]; l++ {
+				if rest := split(i+l, k+1); rest >= 0 {
+					result = max(result, rest+1)
+				}
+			}
+		}
+		best[i][j] = result
+		return result
+	}
+
+	return max(split(0, 0), 0)
+}
@@ -0,0 +1,113 @@
+package fuzzy
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestDistance(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"", "", 0},
+		{"", "gum", 3},
+		{"gum", "gum", 0},
+		{"gmu", "gum", 1},
+		{"kitten", "sitting", 3},
+		{"café", "cafe", 1},
+		{"ab", "ba", 1},
+	}
+	for _, tt := range tests {
+		if got := Distance(tt.a, tt.b); got != tt.want {
+			t.Errorf("Distance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+		if got := Distance(tt.b, tt.a); got != tt.want {
+			t.Errorf("Distance(%q, %q) = %d, want %d", tt.b, tt.a, got, tt.want)
+		}
+	}
+}
+
+func TestWords(t *testing.T) {
+	tests := []struct {
+		name string
+		want []string
+	}{
+		{"", nil},
+		{"gum", []string{"gum"}},
+		{"platformDataPipeline", []string{"platform", "data", "pipeline"}},
+		{"platform_data-pipeline", []string{"platform", "data", "pipeline"}},
+		{"XMLParser2", []string{"xml", "parser", "2"}},
+		{"v2beta", []string{"v", "2", "beta"}},
+		{"--my  repo.", []string{"my", "repo"}},
+	}
+	for _, tt := range tests {
+		if got := Words(tt.name); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Words(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestWordPrefixes(t *testing.T) {
+	words := []string{"platform", "data", "pipeline"}
+	tests := []struct {
+		q    string
+		want int
+	}{
+		{"", 0},
+		{"pdp", 3},
+		{"platdata", 2},
+		{"datapipe", 2},
+		{"dp", 2},
+		{"pp", 2},
+		{"pl", 1},
+		{"platformdatapipeline", 3},
+		{"pdpx", 0},
+		{"dpp", 0},
+		{"x", 0},
+	}
+	for _, tt := range tests {
+		if got := wordPrefixes(tt.q, words); got != tt.want {
+			t.Errorf("wordPrefixes(%q, %q) = %d, want %d", tt.q, words, got, tt.want)
+		}
+	}
+}
+
+func TestExplain(t *testing.T) {
+	tests := []struct {
+		query, name string
+		want        Match
+	}{
+		{"", "gum", Match{}},
+		{"gum", "", Match{}},
+		{"Gum", "gum", Match{Score: 1, Exact: 1, Substring: 1, Words: 0.9, Edit: 1}},
+		{"gum", "gum-cli", Match{Score: 0.75 + 0.25*3/7, Substring: 0.75 + 0.25*3/7, Words: 0.8, Edit: 1 - 4.0/7}},
+		{"pdp", "platform-data-pipeline", Match{Score: 0.9, Words: 0.9, Edit: 1 - 19.0/22}},
+		{"gmu", "gum", Match{Score: 2.0 / 3, Edit: 2.0 / 3}},
+		{"xyz", "gum", Match{}},
+	}
+	for _, tt := range tests {
+		got := Explain(tt.query, tt.name)
+		if !closeMatch(got, tt.want) {
+			t.Errorf("Explain(%q, %q) = %+v, want %+v", tt.query, tt.name, got, tt.want)
+		}
+		if score := Score(tt.query, tt.name); score != got.Score {
+			t.Errorf("Score(%q, %q) = %v, but Explain gives %v", tt.query, tt.name, score, got.Score)
+		}
+	}
+}
+
+func TestOnlyExactScoresOne(t *testing.T) {
+	for _, name := range []string{"gum-cli", "gums", "my-gum", "g-u-m"} {
+		if score := Score("gum", name); score >= 1 {
+			t.Errorf("Score(gum, %q) = %v, want less than 1", name, score)
+		}
+	}
+}
+
+func closeMatch(a, b Match) bool {
+	close := func(x, y float64) bool { return math.Abs(x-y) < 1e-9 }
+	return close(a.Score, b.Score) && close(a.Exact, b.Exact) && close(a.Substring, b.Substring) &&
+		close(a.Words, b.Words) && close(a.Edit, b.Edit)
+}
@@ -0,0 +1,77 @@
+// Package locate finds files through the locate database, which is much
+// faster than walking the file system when the database is up to date.
+package locate
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"time"
+
+	"github.com/shalomb/gum/internal/execx"
+)
+
+// Databases are the locate databases of plocate, mlocate and findutils,
+// in the order they are looked for.
+var Databases = []string{
+	"/var/lib/plocate/plocate.db",
+	"/var/lib/mlocate/mlocate.db",
+	"/var/cache/locate/locatedb",
+	"/var/lib/locate/locatedb",
+}
+
+// ErrNoDatabase is returned by DatabaseAge when there is no locate database.
+var ErrNoDatabase = errors.New("no locate database found")
+
+// Finder finds files by name through an index.
+type Finder interface {
+	// Find returns the paths, symlinks resolved, of all the files and
+	// directories named any of names. The index may be stale, so the
+	// paths need not exist any more.
+	Find(ctx context.Context, names ...string) ([]string, error)
+
+	// DatabaseAge returns how long ago the index was last updated.
+	DatabaseAge() (time.Duration, error)
+}
+
+// Locate is the Finder that runs locate.
+type Locate struct {
+	Runner execx.Runner
+}
+
+// Find implements Finder.
+func (l Locate) Find(ctx context.Context, names ...string) ([]string, error) {
+	// -b '\name' matches the base name exactly.
+	args := []string{"-0", "-b"}
+	for _, name := range names {
+		args = append(args, `\`+name)
+	}
+
+	stdout, _, err := l.Runner.Run(ctx, "", "locate", args...)
+	// locate exits 1 when nothing matches.
+	var exitErr *exec.ExitError
+	if err != nil && !(errors.As(err, &exitErr) && exitErr.ExitCode() == 1 && len(stdout) == 0) {
+		return nil, fmt.Errorf("error running locate: %w", err)
+	}
+
+	var paths []string
+	for _, path := range bytes.Split(stdout, []byte{0}) {
+		if len(path) > 0 {
+			paths = append(paths, string(path))
+		}
+	}
+	return paths, nil
+}
+
+// DatabaseAge implements Finder.
+func (Locate) DatabaseAge() (time.Duration, error) {
+	for _, db := range Databases {
+		if info, err := os.Stat(db); err == nil {
+			return time.Since(info.ModTime()), nil
+		}
+	}
+	return 0, ErrNoDatabase
+}
@@ -0,0 +1,145 @@
+// Package manifest reads the names projects give themselves in their
+// package manifests, such as the module path in go.mod, so that projects
+// can be found by those names as well as by their directory.
+package manifest
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// maxSize is how much of a manifest is read. The names are near the top,
+// and a huge or runaway file must not slow discovery down.
+const maxSize = 64 << 10
+
+// Identifier is a name a project is known by.
+type Identifier struct {
+	// Kind is the manifest the name comes from: go, npm, cargo or python.
+	Kind  string `json:"kind"`
+	Value string `json:"value"`
+}
+
+// parsers read the name from each kind of manifest.
+var parsers = []struct {
+	kind, file string
+	parse      func(data []byte) string
+}{
+	{"go", "go.mod", parseGoMod},
+	{"npm", "package.json", parsePackageJSON},
+	{"cargo", "Cargo.toml", tomlName("package")},
+	{"python", "pyproject.toml", tomlName("project", "tool.poetry")},
+}
+
+// Identifiers returns the names declared by the manifests in dir. Missing,
+// unreadable or malformed manifests are skipped.
+func Identifiers(dir string) []Identifier {
+	var ids []Identifier
+	for _, p := range parsers {
+		data, err := readCapped(filepath.Join(dir, p.file))
+		if err != nil {
+			continue
+		}
+		if name := p.parse(data); name != "" {
+			ids = append(ids, Identifier{Kind: p.kind, Value: name})
+		}
+	}
+	return ids
+}
+
+// readCapped reads up to maxSize bytes of path. If the file is longer, the
+// line cut short is dropped, so that half a name is not taken for one; a
+// line whose newline is the byte just past maxSize is complete and kept.
+func readCapped(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(f, maxSize+1))
+	if err != nil || len(data) <= maxSize {
+		return data, err
+	}
+	return data[:bytes.LastIndexByte(data, '\n')+1], nil
+}
+
+// parseGoMod returns the module path of a go.mod file.
+func parseGoMod(data []byte) string {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "//")
+		if rest, ok := strings.CutPrefix(strings.TrimSpace(line), "module"); ok && rest != "" && (rest[0] == ' ' || rest[0] == '\t') {
+			return unquote(strings.TrimSpace(rest))
+		}
+	}
+	return ""
+}
+
+// parsePackageJSON returns the name in a package.json file.
+func parsePackageJSON(data []byte) string {
+	var pkg struct {
+		Name string `json:"name"`
+	}
+	if json.Unmarshal(data, &pkg) != nil {
+		return ""
+	}
+	return strings.TrimSpace(pkg.Name)
+}
+
+// tomlName returns a parser for the name key of the first of the given
+// TOML tables that has one. Only the simple `name = "value"` form is
+// understood, which is how manifests are written in practice.
+func tomlName(tables ...string) func(data []byte) string {
+	return func(data []byte) string {
+		names := map[string]string{}
+		var table string
+
+		scanner := bufio.NewScanner(bytes.NewReader(data))
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if strings.HasPrefix(line, "[") {
+				table = strings.TrimSpace(strings.Trim(line, "[]"))
+				continue
+			}
+			key, value, ok := strings.Cut(line, "=")
+			if !ok || strings.TrimSpace(key) != "name" {
+				continue
+			}
+			if _, seen := names[table]; !seen {
+				names[table] = tomlString(strings.TrimSpace(value))
+			}
+		}
+
+		for _, t := range tables {
+			if names[t] != "" {
+				return names[t]
+			}
+		}
+		return ""
+	}
+}
+
+// tomlString returns the string a TOML value starts with, without its
+// quotes and any trailing comment.
+func tomlString(value string) string {
+	if len(value) > 0 && (value[0] == '"' || value[0] == '\'') {
+		if end := strings.IndexByte(value[1:], value[0]); end >= 0 {
+			return value[1 : end+1]
+		}
+		return ""
+	}
+	value, _, _ = strings.Cut(value, "#")
+	return strings.TrimSpace(value)
+}
+
+// unquote strips one pair of matching quotes from s.
+func unquote(s string) string {
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'' || s[0] == '`') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
@@ -0,0 +1,146 @@
+package manifest
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseGoMod(t *testing.T) {
+	tests := []struct {
+		data string
+		want string
+	}{
+		{"module github.com/shalomb/gum\n\ngo 1.21\n", "github.com/shalomb/gum"},
+		{"// A comment.\nmodule\texample.com/tabbed // trailing\n", "example.com/tabbed"},
+		{`module "example.com/quoted"` + "\n", "example.com/quoted"},
+		{"// module example.com/commented\n", ""},
+		{"modulex example.com/no\n", ""},
+		{"go 1.21\n", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := parseGoMod([]byte(tt.data)); got != tt.want {
+			t.Errorf("parseGoMod(%q) = %q, want %q", tt.data, got, tt.want)
+		}
+	}
+}
+
+func TestParsePackageJSON(t *testing.T) {
+	tests := []struct {
+		data string
+		want string
+	}{
+		{`{"name": "@shalomb/gum", "version": "1.0.0"}`, "@shalomb/gum"},
+		{`{"name": " padded "}`, "padded"},
+		{`{"version": "1.0.0"}`, ""},
+		{`{"name": 42}`, ""},
+		{`{"name": "truncated`, ""},
+	}
+	for _, tt := range tests {
+		if got := parsePackageJSON([]byte(tt.data)); got != tt.want {
+			t.Errorf("parsePackageJSON(%q) = %q, want %q", tt.data, got, tt.want)
+		}
+	}
+}
+
+func TestTOMLName(t *testing.T) {
+	cargo := tomlName("package")
+	python := tomlName("project", "tool.poetry")
+	tests := []struct {
+		name  string
+		parse func([]byte) string
+		data  string
+		want  string
+	}{
+		{"cargo", cargo, "[package]\nname = \"gum\"\nversion = \"0.1.0\"\n", "gum"},
+		{"cargo comment", cargo, "[package]\nname = \"gum\" # the crate\n", "gum"},
+		{"cargo single quotes", cargo, "[ package ]\nname='gum'\n", "gum"},
+		{"cargo other tables", cargo, "[[bin]]\nname = \"tool\"\n[dependencies]\nname = \"dep\"\n", ""},
+		{"cargo first name", cargo, "[package]\nname = \"gum\"\nname = \"again\"\n", "gum"},
+		{"cargo unterminated", cargo, "[package]\nname = \"gum\n", ""},
+		{"python project", python, "[project]\nname = \"gum-py\"\n", "gum-py"},
+		{"python poetry", python, "[tool.poetry]\nname = \"gum-poetry\"\n", "gum-poetry"},
+		{"python prefers project", python, "[tool.poetry]\nname = \"poetry\"\n\n[project]\nname = \"project\"\n", "project"},
+		{"python top level", python, "name = \"top\"\n[build-system]\n", ""},
+	}
+	for _, tt := range tests {
+		if got := tt.parse([]byte(tt.data)); got != tt.want {
+			t.Errorf("%v: parsed %q as %q, want %q", tt.name, tt.data, got, tt.want)
+		}
+	}
+}
+
+func writeManifest(t *testing.T, dir, name, data string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestIdentifiers(t *testing.T) {
+	dir := t.TempDir()
+	if ids := Identifiers(dir); ids != nil {
+		t.Errorf("Identifiers of an empty directory = %v, want none", ids)
+	}
+
+	writeManifest(t, dir, "pyproject.toml", "[project]\nname = \"gum-py\"\n")
+	writeManifest(t, dir, "package.json", `{"name": "gum-js"}`)
+	writeManifest(t, dir, "Cargo.toml", "[workspace]\nmembers = []\n")
+	writeManifest(t, dir, "go.mod", "module github.com/shalomb/gum\n")
+
+	want := []Identifier{
+		{Kind: "go", Value: "github.com/shalomb/gum"},
+		{Kind: "npm", Value: "gum-js"},
+		{Kind: "python", Value: "gum-py"},
+	}
+	if ids := Identifiers(dir); !reflect.DeepEqual(ids, want) {
+		t.Errorf("Identifiers = %v, want %v", ids, want)
+	}
+}
+
+func TestIdentifiersReadsOnlyMaxSize(t *testing.T) {
+	// The padding leaves a few bytes of the first 64KiB for the module
+	// line, which must not be read as a truncated path.
+	padding := strings.Repeat("// padding\n", maxSize/len("// padding\n"))
+
+	dir := t.TempDir()
+	writeManifest(t, dir, "go.mod", padding+"module example.com/late\n")
+	writeManifest(t, dir, "package.json", `{"description": "`+strings.Repeat("x", maxSize)+`", "name": "late"}`)
+	writeManifest(t, dir, "Cargo.toml", "[package]\n"+strings.Repeat("# padding\n", maxSize/len("# padding\n"))+"name = \"late\"\n")
+	if ids := Identifiers(dir); ids != nil {
+		t.Errorf("Identifiers = %v, want none from past the first %d bytes", ids, maxSize)
+	}
+
+	writeManifest(t, dir, "go.mod", strings.Repeat("// padding\n", 5000)+"module example.com/early\n")
+	want := []Identifier{{Kind: "go", Value: "example.com/early"}}
+	if ids := Identifiers(dir); !reflect.DeepEqual(ids, want) {
+		t.Errorf("Identifiers = %v, want %v", ids, want)
+	}
+}
+
+func TestReadCapped(t *testing.T) {
+	dir := t.TempDir()
+	exact := strings.Repeat("x", maxSize)
+	writeManifest(t, dir, "exact", exact)
+	if data, err := readCapped(filepath.Join(dir, "exact")); err != nil || string(data) != exact {
+		t.Errorf("readCapped of a %d byte file returned %d bytes, %v; want all of it", maxSize, len(data), err)
+	}
+
+	writeManifest(t, dir, "long", "first\n"+exact)
+	if data, err := readCapped(filepath.Join(dir, "long")); err != nil || string(data) != "first\n" {
+		t.Errorf("readCapped of a longer file returned %.20q…, %v; want only its complete lines", data, err)
+	}
+
+	// The newline ending the last line falls just past maxSize.
+	writeManifest(t, dir, "boundary", exact+"\nnext\n")
+	if data, err := readCapped(filepath.Join(dir, "boundary")); err != nil || string(data) != exact+"\n" {
+		t.Errorf("readCapped of a line ending at the cap returned %d bytes, %v; want the line", len(data), err)
+	}
+
+	if _, err := readCapped(filepath.Join(dir, "missing")); err == nil {
+		t.Errorf("readCapped of a missing file succeeded")
+	}
+}
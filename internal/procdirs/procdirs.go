@@ -0,0 +1,27 @@
+// Package procdirs finds the working directories of running processes, in
+// whatever way the platform allows.
+package procdirs
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+)
+
+// ErrUnsupported is returned by Cwds on platforms where gum does not know
+// how to find the working directories of processes.
+var ErrUnsupported = errors.New("listing process working directories is not supported on this platform")
+
+// parseLsof returns the paths in the name ("n") fields of lsof -F output.
+func parseLsof(output []byte) []string {
+	var dirs []string
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if len(line) > 1 && line[0] == 'n' {
+			dirs = append(dirs, line[1:])
+		}
+	}
+	return dirs
+}
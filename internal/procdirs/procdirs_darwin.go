@@ -0,0 +1,32 @@
+//go:build darwin
+
+package procdirs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"os/user"
+
+	"github.com/shalomb/gum/internal/execx"
+)
+
+// Cwds returns the working directory of each process of the current user,
+// as reported by lsof since there is no /proc. A directory appears once
+// per process in it.
+func Cwds(ctx context.Context, r execx.Runner) ([]string, error) {
+	u, err := user.Current()
+	if err != nil {
+		return nil, fmt.Errorf("error finding current user: %w", err)
+	}
+
+	stdout, _, err := r.Run(ctx, "", "lsof", "-d", "cwd", "-a", "-u", u.Username, "-Fn")
+	// lsof exits 1 when it could not inspect some processes, even though
+	// it listed the others.
+	var exitErr *exec.ExitError
+	if err != nil && !(errors.As(err, &exitErr) && len(stdout) > 0) {
+		return nil, fmt.Errorf("error running lsof: %w", err)
+	}
+	return parseLsof(stdout), nil
+}
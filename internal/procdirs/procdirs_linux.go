@@ -0,0 +1,34 @@
+//go:build linux
+
+package procdirs
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	ps "github.com/mitchellh/go-ps"
+	"github.com/shalomb/gum/internal/execx"
+)
+
+// Cwds returns the working directory of each running process that can be
+// inspected, read from /proc/<pid>/cwd. A directory appears once per
+// process in it.
+func Cwds(ctx context.Context, r execx.Runner) ([]string, error) {
+	pslist, err := ps.Processes()
+	if err != nil {
+		return nil, fmt.Errorf("error listing processes: %w", err)
+	}
+
+	var dirs []string
+	for _, p := range pslist {
+		// Processes may exit or belong to other users, so unreadable
+		// entries are expected and skipped.
+		dir, err := os.Readlink(fmt.Sprintf("/proc/%d/cwd", p.Pid()))
+		if err != nil || dir == "" {
+			continue
+		}
+		dirs = append(dirs, dir)
+	}
+	return dirs, nil
+}
@@ -0,0 +1,14 @@
+//go:build !linux && !darwin
+
+package procdirs
+
+import (
+	"context"
+
+	"github.com/shalomb/gum/internal/execx"
+)
+
+// Cwds always fails with ErrUnsupported on this platform.
+func Cwds(ctx context.Context, r execx.Runner) ([]string, error) {
+	return nil, ErrUnsupported
+}
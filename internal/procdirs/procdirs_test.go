@@ -0,0 +1,70 @@
+package procdirs
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/shalomb/gum/internal/execx"
+)
+
+func TestParseLsof(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   []string
+	}{
+		{"empty", "", nil},
+		{
+			name:   "one directory per process",
+			output: "p101\nfcwd\nn/home/me/src/gum\np202\nfcwd\nn/tmp\n",
+			want:   []string{"/home/me/src/gum", "/tmp"},
+		},
+		{
+			name:   "repeated directories",
+			output: "p1\nn/home/me\np2\nn/home/me\n",
+			want:   []string{"/home/me", "/home/me"},
+		},
+		{
+			name:   "spaces and no final newline",
+			output: "p1\nfcwd\nn/home/me/My Projects/a b",
+			want:   []string{"/home/me/My Projects/a b"},
+		},
+		{
+			name:   "other fields and empty names",
+			output: "p1\ncbash\nu501\nn\nfcwd\nn/srv\n",
+			want:   []string{"/srv"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseLsof([]byte(tt.output)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseLsof(%q) = %q, want %q", tt.output, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCwdsFindsOwnDirectory(t *testing.T) {
+	dirs, err := Cwds(context.Background(), execx.Exec{})
+	if errors.Is(err, ErrUnsupported) {
+		t.Skip(err)
+	}
+	if err != nil {
+		t.Fatalf("Cwds failed: %v", err)
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if real, err := filepath.EvalSymlinks(wd); err == nil {
+		wd = real
+	}
+	if !slices.Contains(dirs, wd) {
+		t.Errorf("Cwds = %q, which does not include the test's own directory %v", dirs, wd)
+	}
+}